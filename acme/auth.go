@@ -0,0 +1,94 @@
+package acme
+
+import (
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// authResult is what a successfully authenticated ACME request yields.
+type authResult struct {
+	header  jwsHeader
+	payload []byte
+	account *Account // set whenever the request authenticated via "kid"
+	jwk     *jwk     // the key the request authenticated with, either way
+}
+
+// authenticate verifies body's JWS and binds it to an identity, per RFC
+// 8555 §6.2: every request must carry either "kid" (naming an
+// already-registered account, whose stored public key is used to verify
+// the signature) or, only when allowEmbeddedJWK is true (new-account),
+// an embedded "jwk". A request is never allowed to assert its own
+// identity by embedding a key outside of account creation — otherwise
+// anyone could mint a keypair, set kid to a victim's account ID, and
+// forge requests as that account.
+func (h *Handler) authenticate(body []byte, expectedURL string, allowEmbeddedJWK bool) (*authResult, error) {
+	req, header, err := decodeJWS(body)
+	if err != nil {
+		return nil, err
+	}
+
+	hasKID := header.KID != ""
+	hasJWK := len(header.JWK) != 0
+	if hasKID == hasJWK {
+		return nil, fmt.Errorf("acme: request must carry exactly one of kid or jwk")
+	}
+
+	var (
+		pub     crypto.PublicKey
+		key     *jwk
+		account *Account
+	)
+	switch {
+	case hasKID:
+		// kid is the full account URL handler.go's handleNewAccount
+		// returned as the Location header (RFC 8555 §7.1.2), not a bare
+		// ID, so it needs the same prefix-stripping every other
+		// URL-bearing route in this package applies before hitting the
+		// store.
+		accountID := strings.TrimPrefix(header.KID, h.url(pathNewAccount)+"/")
+		acct, err := h.store.GetACMEAccount(accountID)
+		if err != nil {
+			return nil, fmt.Errorf("acme: unknown account %q: %w", accountID, err)
+		}
+		key = &jwk{}
+		if err := json.Unmarshal(acct.JWK, key); err != nil {
+			return nil, fmt.Errorf("acme: stored account key is corrupt: %w", err)
+		}
+		pub, err = key.publicKey()
+		if err != nil {
+			return nil, err
+		}
+		account = &acct
+
+	case allowEmbeddedJWK:
+		key = &jwk{}
+		if err := json.Unmarshal(header.JWK, key); err != nil {
+			return nil, fmt.Errorf("acme: malformed jwk: %w", err)
+		}
+		pub, err = key.publicKey()
+		if err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("acme: request must carry kid")
+	}
+
+	verified, err := verifyJWS(req, header, expectedURL, pub)
+	if err != nil {
+		return nil, err
+	}
+
+	if !h.nonces.Verify(verified.header.Nonce) {
+		return nil, fmt.Errorf("acme: bad or reused nonce")
+	}
+
+	return &authResult{
+		header:  verified.header,
+		payload: verified.payload,
+		account: account,
+		jwk:     key,
+	}, nil
+}
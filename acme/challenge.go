@@ -0,0 +1,131 @@
+package acme
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudflare/cfssl/log"
+)
+
+// ChallengeStore tracks the challenges issued for pending authorizations
+// and validates a responder's key authorization against the challenge
+// type.
+type ChallengeStore interface {
+	// Put records that token was issued for the given challenge type.
+	Put(ctx context.Context, token, challengeType, identifier string) error
+	// Validate fetches proof of control for token (an HTTP-01 GET or a
+	// DNS-01 TXT lookup, depending on how it was registered) and
+	// compares it against the expected key authorization.
+	Validate(ctx context.Context, token, accountThumbprint string) error
+}
+
+// keyAuthorization is the value a challenge responder must present,
+// per RFC 8555 §8.1.
+func keyAuthorization(token, accountThumbprint string) string {
+	return token + "." + accountThumbprint
+}
+
+// memChallengeStore is an in-memory ChallengeStore suitable for a single
+// instance; multi-replica deployments should back this with certdb or a
+// shared cache instead.
+type memChallengeStore struct {
+	httpClient *http.Client
+	resolver   *net.Resolver
+
+	mu      sync.Mutex
+	entries map[string]challengeEntry
+}
+
+type challengeEntry struct {
+	challengeType string
+	identifier    string
+}
+
+// NewMemChallengeStore creates a ChallengeStore that validates HTTP-01
+// over plain HTTP and DNS-01 via the default resolver.
+func NewMemChallengeStore() ChallengeStore {
+	return &memChallengeStore{
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		resolver:   net.DefaultResolver,
+		entries:    make(map[string]challengeEntry),
+	}
+}
+
+func (s *memChallengeStore) Put(_ context.Context, token, challengeType, identifier string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[token] = challengeEntry{challengeType: challengeType, identifier: identifier}
+	return nil
+}
+
+func (s *memChallengeStore) Validate(ctx context.Context, token, accountThumbprint string) error {
+	s.mu.Lock()
+	entry, ok := s.entries[token]
+	s.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("acme: no challenge registered for token %q", token)
+	}
+
+	want := keyAuthorization(token, accountThumbprint)
+
+	switch entry.challengeType {
+	case ChallengeHTTP01:
+		return s.validateHTTP01(ctx, entry.identifier, token, want)
+	case ChallengeDNS01:
+		return s.validateDNS01(ctx, entry.identifier, want)
+	default:
+		return fmt.Errorf("acme: unsupported challenge type %q", entry.challengeType)
+	}
+}
+
+func (s *memChallengeStore) validateHTTP01(ctx context.Context, identifier, token, want string) error {
+	url := fmt.Sprintf("http://%s/.well-known/acme-challenge/%s", identifier, token)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("acme: http-01 fetch failed for %s: %w", identifier, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return err
+	}
+
+	got := strings.TrimSpace(string(body))
+	if got != want {
+		log.Warningf("acme: http-01 challenge mismatch for %s", identifier)
+		return fmt.Errorf("acme: http-01 response did not match expected key authorization")
+	}
+	return nil
+}
+
+func (s *memChallengeStore) validateDNS01(ctx context.Context, identifier, want string) error {
+	name := "_acme-challenge." + strings.TrimPrefix(identifier, "*.")
+	records, err := s.resolver.LookupTXT(ctx, name)
+	if err != nil {
+		return fmt.Errorf("acme: dns-01 lookup failed for %s: %w", name, err)
+	}
+
+	sum := sha256.Sum256([]byte(want))
+	wantDigest := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	for _, r := range records {
+		if r == wantDigest {
+			return nil
+		}
+	}
+	return fmt.Errorf("acme: dns-01 response did not match expected digest for %s", name)
+}
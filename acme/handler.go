@@ -0,0 +1,747 @@
+// Package acme implements an RFC 8555 (ACME v2) front-end that reuses
+// the same signer.Signer and bundler.Bundler already wired up for
+// api/newcert, so certificates issued through ACME go through identical
+// signing, bundling and OCSP-record-insertion logic.
+package acme
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cloudflare/cfssl/bundler"
+	"github.com/cloudflare/cfssl/certdb"
+	"github.com/cloudflare/cfssl/log"
+	"github.com/cloudflare/cfssl/ocsp"
+	"github.com/cloudflare/cfssl/signer"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	stdocsp "golang.org/x/crypto/ocsp"
+)
+
+// tracer provides spans for ACME request handling, matching the
+// instrumentation api/newcert.Handler.Handle carries so issuance is
+// traceable end-to-end regardless of which front-end it came through.
+var tracer = otel.Tracer("cfssl/acme")
+
+const (
+	pathDirectory  = "/acme/directory"
+	pathNewNonce   = "/acme/new-nonce"
+	pathNewAccount = "/acme/new-account"
+	pathNewOrder   = "/acme/new-order"
+	pathOrder      = "/acme/order/"
+	pathAuthz      = "/acme/authz/"
+	pathChallenge  = "/acme/challenge/"
+	pathFinalize   = "/acme/finalize/"
+	pathCert       = "/acme/cert/"
+	pathRevokeCert = "/acme/revoke-cert"
+)
+
+// Handler is an http.Handler implementing the ACME v2 endpoints needed to
+// issue and revoke certificates.
+type Handler struct {
+	signer  signer.Signer
+	bundler *bundler.Bundler
+
+	ocspSigner ocsp.Signer
+	store      Store
+	challenges ChallengeStore
+	nonces     *nonceSource
+
+	externalAccountBound  bool
+	wildcardIssuanceAllow bool
+
+	baseURL string
+}
+
+// NewHandler creates a Handler backed by signer for issuance and store
+// for ACME object persistence, mirroring newcert.NewHandler's
+// option-functional construction.
+func NewHandler(signer signer.Signer, store Store, baseURL string, opts ...Opt) (http.Handler, error) {
+	o := options{challengeStore: NewMemChallengeStore()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	b, err := bundler.NewBundler(o.caBundleFile, o.intBundleFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Handler{
+		signer:                signer,
+		bundler:               b,
+		ocspSigner:            o.ocspSigner,
+		store:                 store,
+		challenges:            o.challengeStore,
+		nonces:                newNonceSource(),
+		externalAccountBound:  o.externalAccountBound,
+		wildcardIssuanceAllow: o.wildcardIssuanceAllow,
+		baseURL:               strings.TrimSuffix(baseURL, "/"),
+	}, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "acme."+r.URL.Path)
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	w.Header().Set("Replay-Nonce", h.mustNonce(w))
+
+	switch {
+	case r.URL.Path == pathDirectory:
+		h.handleDirectory(w, r)
+	case r.URL.Path == pathNewNonce:
+		h.handleNewNonce(w, r)
+	case r.URL.Path == pathNewAccount:
+		h.handleNewAccount(w, r)
+	case r.URL.Path == pathNewOrder:
+		h.handleNewOrder(w, r)
+	case strings.HasPrefix(r.URL.Path, pathOrder):
+		h.handleOrder(w, r, strings.TrimPrefix(r.URL.Path, pathOrder))
+	case strings.HasPrefix(r.URL.Path, pathAuthz):
+		h.handleAuthz(w, r, strings.TrimPrefix(r.URL.Path, pathAuthz))
+	case strings.HasPrefix(r.URL.Path, pathChallenge):
+		h.handleChallenge(w, r, strings.TrimPrefix(r.URL.Path, pathChallenge))
+	case strings.HasPrefix(r.URL.Path, pathFinalize):
+		h.handleFinalize(w, r, strings.TrimPrefix(r.URL.Path, pathFinalize))
+	case strings.HasPrefix(r.URL.Path, pathCert):
+		h.handleCert(w, r, strings.TrimPrefix(r.URL.Path, pathCert))
+	case r.URL.Path == pathRevokeCert:
+		h.handleRevokeCert(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// recordErr marks span as failed and returns err unchanged, so call sites
+// can write `return recordErr(span, err)` without losing the original
+// error, mirroring newcert.Handler.Handle's recordErr helper.
+func recordErr(span trace.Span, err error) error {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// storeOp runs fn under its own child span, giving Store calls the same
+// per-operation tracing certdb/sql gets from mensureQueryTime, without
+// requiring Store implementations to instrument themselves.
+func (h *Handler) storeOp(ctx context.Context, name string, fn func() error) error {
+	_, span := tracer.Start(ctx, "acme.store."+name)
+	defer span.End()
+	return recordErr(span, fn())
+}
+
+func (h *Handler) url(path string) string {
+	return h.baseURL + path
+}
+
+func (h *Handler) mustNonce(w http.ResponseWriter) string {
+	nonce, err := h.nonces.New()
+	if err != nil {
+		log.Errorf("acme: failed to issue nonce: %v", err)
+	}
+	return nonce
+}
+
+func (h *Handler) handleDirectory(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Directory{
+		NewNonce:   h.url(pathNewNonce),
+		NewAccount: h.url(pathNewAccount),
+		NewOrder:   h.url(pathNewOrder),
+		RevokeCert: h.url(pathRevokeCert),
+		Meta: &Meta{
+			ExternalAccountRequired: h.externalAccountBound,
+		},
+	})
+}
+
+func (h *Handler) handleNewNonce(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) handleNewAccount(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "acme.newAccount")
+	defer span.End()
+
+	body, err := readBody(r)
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, recordErr(span, err))
+		return
+	}
+
+	// new-account is the only endpoint allowed to authenticate with an
+	// embedded jwk, since no account (and thus no kid) exists yet.
+	result, err := h.authenticate(body, h.url(pathNewAccount), true)
+	if err != nil {
+		writeProblem(w, http.StatusUnauthorized, recordErr(span, err))
+		return
+	}
+
+	var req struct {
+		Contact                []string        `json:"contact"`
+		TermsOfServiceAgreed   bool            `json:"termsOfServiceAgreed"`
+		ExternalAccountBinding json.RawMessage `json:"externalAccountBinding"`
+	}
+	if err := json.Unmarshal(result.payload, &req); err != nil {
+		writeProblem(w, http.StatusBadRequest, recordErr(span, err))
+		return
+	}
+	if h.externalAccountBound && len(req.ExternalAccountBinding) == 0 {
+		writeProblem(w, http.StatusBadRequest, recordErr(span, fmt.Errorf("acme: externalAccountBinding is required")))
+		return
+	}
+
+	jwkBytes, err := json.Marshal(result.jwk)
+	if err != nil {
+		writeProblem(w, http.StatusInternalServerError, recordErr(span, err))
+		return
+	}
+
+	id := randomID()
+	account := Account{
+		ID:                   id,
+		Status:               StatusValid,
+		Contact:              req.Contact,
+		TermsOfServiceAgreed: req.TermsOfServiceAgreed,
+		JWK:                  jwkBytes,
+		OrdersURL:            h.url(pathOrder) + id + "/orders",
+	}
+	span.SetAttributes(attribute.String("acme.account_id", id))
+	if err := h.storeOp(ctx, "InsertACMEAccount", func() error { return h.store.InsertACMEAccount(account) }); err != nil {
+		writeProblem(w, http.StatusInternalServerError, recordErr(span, err))
+		return
+	}
+
+	w.Header().Set("Location", h.url(pathNewAccount)+"/"+id)
+	writeJSON(w, http.StatusCreated, account)
+}
+
+func (h *Handler) handleNewOrder(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "acme.newOrder")
+	defer span.End()
+
+	body, err := readBody(r)
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, recordErr(span, err))
+		return
+	}
+
+	// new-order must be bound to an already-registered account via kid;
+	// an embedded jwk is only legal on new-account (RFC 8555 §6.2).
+	result, err := h.authenticate(body, h.url(pathNewOrder), false)
+	if err != nil {
+		writeProblem(w, http.StatusUnauthorized, recordErr(span, err))
+		return
+	}
+	account := result.account
+
+	var req struct {
+		Identifiers []Identifier `json:"identifiers"`
+	}
+	if err := json.Unmarshal(result.payload, &req); err != nil {
+		writeProblem(w, http.StatusBadRequest, recordErr(span, err))
+		return
+	}
+
+	orderID := randomID()
+	span.SetAttributes(attribute.String("acme.order_id", orderID))
+	order := Order{
+		ID:          orderID,
+		AccountID:   account.ID,
+		Status:      StatusPending,
+		Identifiers: req.Identifiers,
+		FinalizeURL: h.url(pathFinalize) + orderID,
+	}
+
+	for _, ident := range req.Identifiers {
+		wildcard := strings.HasPrefix(ident.Value, "*.")
+		if wildcard && !h.wildcardIssuanceAllow {
+			writeProblem(w, http.StatusForbidden, recordErr(span, fmt.Errorf("acme: wildcard issuance is disabled")))
+			return
+		}
+
+		authzID := randomID()
+		token := randomID()
+		chalType := ChallengeHTTP01
+		if wildcard {
+			chalType = ChallengeDNS01
+		}
+
+		if err := h.challenges.Put(ctx, token, chalType, ident.Value); err != nil {
+			writeProblem(w, http.StatusInternalServerError, recordErr(span, err))
+			return
+		}
+
+		authz := Authorization{
+			ID:         authzID,
+			OrderID:    orderID,
+			Identifier: ident,
+			Status:     StatusPending,
+			Wildcard:   wildcard,
+			Challenges: []Challenge{{
+				Type:   chalType,
+				URL:    h.url(pathChallenge) + authzID,
+				Status: StatusPending,
+				Token:  token,
+			}},
+		}
+		if err := h.storeOp(ctx, "InsertAuthorization", func() error { return h.store.InsertAuthorization(authz) }); err != nil {
+			writeProblem(w, http.StatusInternalServerError, err)
+			return
+		}
+		order.AuthorizationURLs = append(order.AuthorizationURLs, h.url(pathAuthz)+authzID)
+	}
+
+	if err := h.storeOp(ctx, "InsertOrder", func() error { return h.store.InsertOrder(order) }); err != nil {
+		writeProblem(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Location", h.url(pathOrder)+orderID)
+	writeJSON(w, http.StatusCreated, order)
+}
+
+func (h *Handler) handleOrder(w http.ResponseWriter, r *http.Request, id string) {
+	order, err := h.store.GetOrder(id)
+	if err != nil {
+		writeProblem(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, order)
+}
+
+func (h *Handler) handleAuthz(w http.ResponseWriter, r *http.Request, id string) {
+	authz, err := h.store.GetAuthorization(id)
+	if err != nil {
+		writeProblem(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, authz)
+}
+
+// handleChallenge responds to a challenge POST by kicking off validation
+// and marking the authorization valid/invalid based on the outcome. RFC
+// 8555 has the server validate asynchronously and the client poll; for
+// simplicity this implementation validates inline.
+func (h *Handler) handleChallenge(w http.ResponseWriter, r *http.Request, authzID string) {
+	ctx, span := tracer.Start(r.Context(), "acme.challenge")
+	defer span.End()
+	span.SetAttributes(attribute.String("acme.authz_id", authzID))
+
+	body, err := readBody(r)
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, recordErr(span, err))
+		return
+	}
+
+	authz, err := h.store.GetAuthorization(authzID)
+	if err != nil {
+		writeProblem(w, http.StatusNotFound, recordErr(span, err))
+		return
+	}
+	if len(authz.Challenges) == 0 {
+		writeProblem(w, http.StatusInternalServerError, recordErr(span, fmt.Errorf("acme: authorization has no challenges")))
+		return
+	}
+	chal := authz.Challenges[0]
+
+	result, err := h.authenticate(body, chal.URL, false)
+	if err != nil {
+		writeProblem(w, http.StatusUnauthorized, recordErr(span, err))
+		return
+	}
+
+	thumbprint, err := result.jwk.thumbprint()
+	if err != nil {
+		writeProblem(w, http.StatusInternalServerError, recordErr(span, err))
+		return
+	}
+
+	chal.Status = StatusProcessing
+	if err := h.challenges.Validate(ctx, chal.Token, thumbprint); err != nil {
+		log.Warningf("acme: challenge validation failed for authz %s: %v", authzID, err)
+		chal.Status = StatusInvalid
+		chal.Error = &Problem{Type: "urn:ietf:params:acme:error:incorrectResponse", Detail: err.Error()}
+		authz.Status = StatusInvalid
+	} else {
+		chal.Status = StatusValid
+		authz.Status = StatusValid
+	}
+	authz.Challenges[0] = chal
+
+	if err := h.storeOp(ctx, "UpdateAuthorization", func() error { return h.store.UpdateAuthorization(authz) }); err != nil {
+		writeProblem(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if authz.Status == StatusValid {
+		if err := h.maybeMarkOrderReady(ctx, authz.OrderID); err != nil {
+			log.Warningf("acme: failed to update order %s readiness: %v", authz.OrderID, err)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, chal)
+}
+
+// maybeMarkOrderReady transitions orderID to "ready" once every one of its
+// authorizations has reached "valid" (RFC 8555 §7.1.6's pending->ready
+// edge). handleFinalize refuses to sign unless an order is ready, so this
+// is the only place that edge is taken.
+func (h *Handler) maybeMarkOrderReady(ctx context.Context, orderID string) error {
+	order, err := h.store.GetOrder(orderID)
+	if err != nil {
+		return err
+	}
+	if order.Status != StatusPending {
+		return nil
+	}
+
+	authzs, err := h.loadAuthorizations(order)
+	if err != nil {
+		return err
+	}
+	if !allAuthorizationsValid(authzs) {
+		return nil
+	}
+
+	order.Status = StatusReady
+	return h.storeOp(ctx, "UpdateOrder", func() error { return h.store.UpdateOrder(order) })
+}
+
+// loadAuthorizations fetches every authorization order.AuthorizationURLs
+// points at.
+func (h *Handler) loadAuthorizations(order Order) ([]Authorization, error) {
+	authzs := make([]Authorization, 0, len(order.AuthorizationURLs))
+	for _, authzURL := range order.AuthorizationURLs {
+		authz, err := h.store.GetAuthorization(strings.TrimPrefix(authzURL, h.url(pathAuthz)))
+		if err != nil {
+			return nil, err
+		}
+		authzs = append(authzs, authz)
+	}
+	return authzs, nil
+}
+
+// allAuthorizationsValid reports whether every authorization in authzs has
+// reached "valid" — the condition RFC 8555 §7.1.6 requires before an
+// order may advance to "ready", and that handleFinalize re-checks before
+// signing, so a client can never skip domain-control validation by
+// finalizing an order whose authorizations never completed.
+func allAuthorizationsValid(authzs []Authorization) bool {
+	if len(authzs) == 0 {
+		return false
+	}
+	for _, a := range authzs {
+		if a.Status != StatusValid {
+			return false
+		}
+	}
+	return true
+}
+
+func (h *Handler) handleFinalize(w http.ResponseWriter, r *http.Request, orderID string) {
+	ctx, span := tracer.Start(r.Context(), "acme.finalize")
+	defer span.End()
+	span.SetAttributes(attribute.String("acme.order_id", orderID))
+
+	body, err := readBody(r)
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, recordErr(span, err))
+		return
+	}
+
+	order, err := h.store.GetOrder(orderID)
+	if err != nil {
+		writeProblem(w, http.StatusNotFound, recordErr(span, err))
+		return
+	}
+
+	result, err := h.authenticate(body, h.url(pathFinalize)+orderID, false)
+	if err != nil {
+		writeProblem(w, http.StatusUnauthorized, recordErr(span, err))
+		return
+	}
+	if result.account.ID != order.AccountID {
+		writeProblem(w, http.StatusUnauthorized, recordErr(span, fmt.Errorf("acme: order %s does not belong to this account", orderID)))
+		return
+	}
+
+	// The whole point of ACME is that a certificate is only issued once
+	// every identifier in the order has actually been proven, so finalize
+	// must refuse to sign anything until the order carries that proof:
+	// every authorization reached "valid" and the order itself advanced
+	// to "ready" (handleChallenge is the only place that happens).
+	if order.Status != StatusReady {
+		writeProblem(w, http.StatusForbidden, recordErr(span, fmt.Errorf("acme: order %s is not ready for finalization", orderID)))
+		return
+	}
+	authzs, err := h.loadAuthorizations(order)
+	if err != nil {
+		writeProblem(w, http.StatusInternalServerError, recordErr(span, err))
+		return
+	}
+	if !allAuthorizationsValid(authzs) {
+		writeProblem(w, http.StatusForbidden, recordErr(span, fmt.Errorf("acme: order %s has authorizations that are not valid", orderID)))
+		return
+	}
+
+	var req struct {
+		CSR string `json:"csr"`
+	}
+	if err := json.Unmarshal(result.payload, &req); err != nil {
+		writeProblem(w, http.StatusBadRequest, recordErr(span, err))
+		return
+	}
+	csrDER, err := base64.RawURLEncoding.DecodeString(req.CSR)
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, recordErr(span, err))
+		return
+	}
+	csrPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER})
+
+	certBytes, err := h.signer.Sign(signer.SignRequest{Request: string(csrPEM)})
+	if err != nil {
+		writeProblem(w, http.StatusInternalServerError, recordErr(span, err))
+		return
+	}
+
+	bundle, err := h.bundler.BundleFromPEMorDER(certBytes, nil, bundler.Optimal, "")
+	if err != nil {
+		writeProblem(w, http.StatusInternalServerError, recordErr(span, err))
+		return
+	}
+
+	if h.ocspSigner != nil {
+		if err := h.insertOCSPRecord(bundle); err != nil {
+			log.Critical("acme: unable to insert OCSP response: ", err)
+		}
+	}
+
+	serial := bundle.Cert.SerialNumber.String()
+	if err := h.storeOp(ctx, "InsertCertificatePEM", func() error {
+		return h.store.InsertCertificatePEM(serial, encodeChainPEM(bundle.Chain))
+	}); err != nil {
+		writeProblem(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	order.Status = StatusValid
+	order.CertificateSerial = serial
+	order.CertificateURL = h.url(pathCert) + serial
+	if err := h.storeOp(ctx, "UpdateOrder", func() error { return h.store.UpdateOrder(order) }); err != nil {
+		writeProblem(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, order)
+}
+
+// encodeChainPEM concatenates chain (leaf-first, as bundler.Bundle.Chain
+// stores it) into the PEM sequence handleCert serves as
+// "application/pem-certificate-chain" (RFC 8555 §7.4.2).
+func encodeChainPEM(chain []*x509.Certificate) []byte {
+	var out []byte
+	for _, c := range chain {
+		out = append(out, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})...)
+	}
+	return out
+}
+
+// insertOCSPRecord mirrors newcert.Handler.Handle's OCSP-record
+// insertion so certificates issued via ACME are indistinguishable, from
+// certdb's point of view, from those issued via api/newcert.
+func (h *Handler) insertOCSPRecord(bundle *bundler.Bundle) error {
+	resp, err := h.ocspSigner.Sign(ocsp.SignRequest{
+		Certificate: bundle.Cert,
+		Status:      "good",
+	})
+	if err != nil {
+		return err
+	}
+
+	parsed, err := stdocsp.ParseResponse(resp, nil)
+	if err != nil {
+		return err
+	}
+
+	return h.signer.GetDBAccessor().InsertOCSP(certdb.OCSPRecord{
+		Serial: bundle.Cert.SerialNumber.String(),
+		AKI:    hex.EncodeToString(bundle.Cert.AuthorityKeyId),
+		Body:   string(resp),
+		Expiry: parsed.NextUpdate,
+	})
+}
+
+func (h *Handler) handleCert(w http.ResponseWriter, r *http.Request, serial string) {
+	_, span := tracer.Start(r.Context(), "acme.cert")
+	defer span.End()
+	span.SetAttributes(attribute.String("acme.certificate_serial", serial))
+
+	body, err := readBody(r)
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, recordErr(span, err))
+		return
+	}
+
+	result, err := h.authenticate(body, h.url(pathCert)+serial, false)
+	if err != nil {
+		writeProblem(w, http.StatusUnauthorized, recordErr(span, err))
+		return
+	}
+
+	order, err := h.store.GetOrderByCertificateSerial(serial)
+	if err != nil {
+		writeProblem(w, http.StatusNotFound, recordErr(span, err))
+		return
+	}
+	if result.account.ID != order.AccountID {
+		writeProblem(w, http.StatusUnauthorized, recordErr(span, fmt.Errorf("acme: certificate %s does not belong to this account", serial)))
+		return
+	}
+
+	chainPEM, err := h.store.GetCertificatePEM(serial)
+	if err != nil {
+		writeProblem(w, http.StatusNotFound, recordErr(span, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pem-certificate-chain")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(chainPEM)
+}
+
+// handleRevokeCert implements RFC 8555 §7.6 revocation authenticated by
+// the issuing account's kid. The spec also allows a client with no
+// account at all to authenticate with the certificate's own key instead;
+// that path isn't implemented here.
+func (h *Handler) handleRevokeCert(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "acme.revokeCert")
+	defer span.End()
+
+	body, err := readBody(r)
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, recordErr(span, err))
+		return
+	}
+
+	result, err := h.authenticate(body, h.url(pathRevokeCert), false)
+	if err != nil {
+		writeProblem(w, http.StatusUnauthorized, recordErr(span, err))
+		return
+	}
+
+	var req struct {
+		Certificate string `json:"certificate"`
+		Reason      int    `json:"reason"`
+	}
+	if err := json.Unmarshal(result.payload, &req); err != nil {
+		writeProblem(w, http.StatusBadRequest, recordErr(span, err))
+		return
+	}
+	der, err := base64.RawURLEncoding.DecodeString(req.Certificate)
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, recordErr(span, err))
+		return
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, recordErr(span, err))
+		return
+	}
+	serial := cert.SerialNumber.String()
+	span.SetAttributes(attribute.String("acme.certificate_serial", serial))
+
+	order, err := h.store.GetOrderByCertificateSerial(serial)
+	if err != nil {
+		writeProblem(w, http.StatusNotFound, recordErr(span, err))
+		return
+	}
+	if result.account.ID != order.AccountID {
+		writeProblem(w, http.StatusUnauthorized, recordErr(span, fmt.Errorf("acme: certificate %s does not belong to this account", serial)))
+		return
+	}
+
+	if h.ocspSigner != nil {
+		if err := h.revokeOCSPRecord(ctx, cert); err != nil {
+			writeProblem(w, http.StatusInternalServerError, recordErr(span, err))
+			return
+		}
+	}
+
+	order.Status = StatusRevoked
+	if err := h.storeOp(ctx, "UpdateOrder", func() error { return h.store.UpdateOrder(order) }); err != nil {
+		writeProblem(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// revokeOCSPRecord re-signs cert's OCSP response as revoked and upserts
+// it, the same accessor call the refresher uses to keep OCSP responses
+// current.
+func (h *Handler) revokeOCSPRecord(ctx context.Context, cert *x509.Certificate) error {
+	resp, err := h.ocspSigner.Sign(ocsp.SignRequest{
+		Certificate: cert,
+		Status:      "revoked",
+		RevokedAt:   time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	parsed, err := stdocsp.ParseResponse(resp, nil)
+	if err != nil {
+		return err
+	}
+
+	serial := cert.SerialNumber.String()
+	aki := hex.EncodeToString(cert.AuthorityKeyId)
+	return h.storeOp(ctx, "UpsertOCSP", func() error {
+		return h.signer.GetDBAccessor().UpsertOCSP(serial, aki, string(resp), parsed.NextUpdate)
+	})
+}
+
+// maxBodyBytes bounds the size of an ACME request body.
+const maxBodyBytes = 1 << 20
+
+func readBody(r *http.Request) ([]byte, error) {
+	defer r.Body.Close()
+	return io.ReadAll(io.LimitReader(r.Body, maxBodyBytes))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeProblem(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(Problem{
+		Type:   "urn:ietf:params:acme:error:malformed",
+		Detail: err.Error(),
+		Status: status,
+	})
+}
+
+func randomID() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
@@ -0,0 +1,49 @@
+package acme
+
+import "testing"
+
+func TestAllAuthorizationsValid(t *testing.T) {
+	cases := []struct {
+		name   string
+		authzs []Authorization
+		want   bool
+	}{
+		{name: "no authorizations", authzs: nil, want: false},
+		{
+			name:   "single pending authorization",
+			authzs: []Authorization{{ID: "a1", Status: StatusPending}},
+			want:   false,
+		},
+		{
+			name:   "single valid authorization",
+			authzs: []Authorization{{ID: "a1", Status: StatusValid}},
+			want:   true,
+		},
+		{
+			name: "one of several still pending",
+			authzs: []Authorization{
+				{ID: "a1", Status: StatusValid},
+				{ID: "a2", Status: StatusPending},
+			},
+			want: false,
+		},
+		{
+			name: "all valid",
+			authzs: []Authorization{
+				{ID: "a1", Status: StatusValid},
+				{ID: "a2", Status: StatusValid},
+			},
+			want: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// handleFinalize must never sign a certificate for an order
+			// whose authorizations haven't all actually been proven.
+			if got := allAuthorizationsValid(c.authzs); got != c.want {
+				t.Errorf("allAuthorizationsValid(%+v) = %v, want %v", c.authzs, got, c.want)
+			}
+		})
+	}
+}
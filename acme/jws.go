@@ -0,0 +1,208 @@
+package acme
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
+// jwsRequest is the flattened JSON JWS serialization ACME clients send
+// (RFC 8555 §6.2).
+type jwsRequest struct {
+	Protected string `json:"protected"`
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"`
+}
+
+// jwsHeader is the subset of the protected header this package acts on.
+type jwsHeader struct {
+	Alg   string          `json:"alg"`
+	Nonce string          `json:"nonce"`
+	URL   string          `json:"url"`
+	KID   string          `json:"kid,omitempty"`
+	JWK   json.RawMessage `json:"jwk,omitempty"`
+}
+
+// jwk is the minimal JSON Web Key representation this package verifies
+// against: RSA or EC public keys (RFC 7518 §6).
+type jwk struct {
+	Kty string `json:"kty"`
+	// EC
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+}
+
+// verifiedJWS is the result of successfully verifying a jwsRequest.
+type verifiedJWS struct {
+	header  jwsHeader
+	payload []byte
+}
+
+// decodeJWS unmarshals raw as a flattened-JSON JWS and decodes its
+// protected header, without verifying the signature. Callers use the
+// returned header to decide which key the signature must be checked
+// against (see verifyJWS) before trusting anything else about the
+// request.
+func decodeJWS(raw []byte) (*jwsRequest, jwsHeader, error) {
+	var req jwsRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return nil, jwsHeader{}, fmt.Errorf("acme: malformed JWS: %w", err)
+	}
+
+	protectedBytes, err := base64.RawURLEncoding.DecodeString(req.Protected)
+	if err != nil {
+		return nil, jwsHeader{}, fmt.Errorf("acme: malformed protected header: %w", err)
+	}
+	var header jwsHeader
+	if err := json.Unmarshal(protectedBytes, &header); err != nil {
+		return nil, jwsHeader{}, fmt.Errorf("acme: malformed protected header: %w", err)
+	}
+
+	return &req, header, nil
+}
+
+// verifyJWS checks expectedURL against the protected header's "url", then
+// verifies the signature against pub. pub must be the key the caller has
+// already decided this request is allowed to authenticate as (see
+// decodeJWS and Handler.authenticate) — this function never derives pub
+// from the request itself, so a request cannot pick its own identity by
+// embedding a throwaway key.
+func verifyJWS(req *jwsRequest, header jwsHeader, expectedURL string, pub crypto.PublicKey) (*verifiedJWS, error) {
+	if header.URL != expectedURL {
+		return nil, fmt.Errorf("acme: JWS url %q does not match request URL %q", header.URL, expectedURL)
+	}
+
+	signingInput := []byte(req.Protected + "." + req.Payload)
+	sig, err := base64.RawURLEncoding.DecodeString(req.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("acme: malformed signature: %w", err)
+	}
+	if err := verifySignature(pub, header.Alg, signingInput, sig); err != nil {
+		return nil, err
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(req.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("acme: malformed payload: %w", err)
+	}
+
+	return &verifiedJWS{header: header, payload: payload}, nil
+}
+
+func verifySignature(pub crypto.PublicKey, alg string, signingInput, sig []byte) error {
+	digest := sha256.Sum256(signingInput)
+
+	switch alg {
+	case "RS256":
+		rsaPub, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("acme: RS256 signature with non-RSA key")
+		}
+		if err := rsa.VerifyPKCS1v15(rsaPub, crypto.SHA256, digest[:], sig); err != nil {
+			return fmt.Errorf("acme: signature verification failed: %w", err)
+		}
+	case "ES256":
+		ecPub, ok := pub.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("acme: ES256 signature with non-EC key")
+		}
+		if len(sig) != 64 {
+			return fmt.Errorf("acme: malformed ES256 signature")
+		}
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(ecPub, digest[:], r, s) {
+			return fmt.Errorf("acme: signature verification failed")
+		}
+	default:
+		return fmt.Errorf("acme: unsupported JWS algorithm %q", alg)
+	}
+	return nil
+}
+
+func (k *jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("acme: malformed RSA jwk modulus: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("acme: malformed RSA jwk exponent: %w", err)
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		curve, err := ecCurve(k.Crv)
+		if err != nil {
+			return nil, err
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("acme: malformed EC jwk x: %w", err)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("acme: malformed EC jwk y: %w", err)
+		}
+		return &ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+	default:
+		return nil, fmt.Errorf("acme: unsupported jwk kty %q", k.Kty)
+	}
+}
+
+func ecCurve(crv string) (elliptic.Curve, error) {
+	switch crv {
+	case "P-256":
+		return elliptic.P256(), nil
+	default:
+		return nil, fmt.Errorf("acme: unsupported jwk crv %q", crv)
+	}
+}
+
+// thumbprint computes the JWK thumbprint (RFC 7638) used to build a
+// challenge's expected key authorization.
+func (k *jwk) thumbprint() (string, error) {
+	var canonical []byte
+	var err error
+	switch k.Kty {
+	case "RSA":
+		canonical, err = json.Marshal(struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{k.E, k.Kty, k.N})
+	case "EC":
+		canonical, err = json.Marshal(struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{k.Crv, k.Kty, k.X, k.Y})
+	default:
+		return "", fmt.Errorf("acme: unsupported jwk kty %q", k.Kty)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(canonical)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
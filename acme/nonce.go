@@ -0,0 +1,64 @@
+package acme
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"sync"
+	"time"
+)
+
+// nonceLifetime bounds how long an issued nonce remains acceptable,
+// per RFC 8555 §6.5.
+const nonceLifetime = 1 * time.Hour
+
+// nonceSource issues and single-use-verifies replay-protection nonces
+// (RFC 8555 §6.5). It is a simple in-memory pool; multi-replica
+// deployments should back this with a shared store instead.
+type nonceSource struct {
+	mu     sync.Mutex
+	active map[string]time.Time
+}
+
+func newNonceSource() *nonceSource {
+	return &nonceSource{active: make(map[string]time.Time)}
+}
+
+// New issues a fresh nonce.
+func (n *nonceSource) New() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	nonce := base64.RawURLEncoding.EncodeToString(buf)
+
+	n.mu.Lock()
+	n.evictExpiredLocked()
+	n.active[nonce] = time.Now().Add(nonceLifetime)
+	n.mu.Unlock()
+
+	return nonce, nil
+}
+
+// Verify consumes nonce, returning true exactly once for a nonce
+// previously returned by New and not yet expired or consumed.
+func (n *nonceSource) Verify(nonce string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	expiry, ok := n.active[nonce]
+	if !ok || time.Now().After(expiry) {
+		return false
+	}
+	delete(n.active, nonce)
+	return true
+}
+
+// evictExpiredLocked drops expired nonces; callers must hold n.mu.
+func (n *nonceSource) evictExpiredLocked() {
+	now := time.Now()
+	for nonce, expiry := range n.active {
+		if now.After(expiry) {
+			delete(n.active, nonce)
+		}
+	}
+}
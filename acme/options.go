@@ -0,0 +1,58 @@
+package acme
+
+import "github.com/cloudflare/cfssl/ocsp"
+
+type options struct {
+	ocspSigner            ocsp.Signer
+	caBundleFile          string
+	intBundleFile         string
+	challengeStore        ChallengeStore
+	externalAccountBound  bool
+	wildcardIssuanceAllow bool
+}
+
+// Opt is a functional option for configuring a new Handler, mirroring
+// newcert.Opt.
+type Opt func(*options)
+
+// WithOCSPSigner sets the OCSP signer used to insert an OCSPRecord for
+// each finalized certificate, exactly as newcert.WithOCSPSigner does.
+func WithOCSPSigner(signer ocsp.Signer) Opt {
+	return func(o *options) {
+		o.ocspSigner = signer
+	}
+}
+
+// WithBundler sets the CA and intermediate bundle files used to build
+// the chain returned alongside a finalized certificate.
+func WithBundler(caBundleFile, intBundleFile string) Opt {
+	return func(o *options) {
+		o.caBundleFile = caBundleFile
+		o.intBundleFile = intBundleFile
+	}
+}
+
+// WithChallengeStore sets the ChallengeStore used to validate HTTP-01 and
+// DNS-01 challenges. Defaults to an in-memory store if not supplied.
+func WithChallengeStore(store ChallengeStore) Opt {
+	return func(o *options) {
+		o.challengeStore = store
+	}
+}
+
+// WithExternalAccountBinding requires new-account requests to carry a
+// non-empty externalAccountBinding (RFC 8555 §7.3.4). Only its presence is
+// checked; its contents are not verified. Disabled by default.
+func WithExternalAccountBinding(required bool) Opt {
+	return func(o *options) {
+		o.externalAccountBound = required
+	}
+}
+
+// WithWildcardIssuance allows orders for identifiers like "*.example.com"
+// (which must use dns-01). Disabled by default.
+func WithWildcardIssuance(allowed bool) Opt {
+	return func(o *options) {
+		o.wildcardIssuanceAllow = allowed
+	}
+}
@@ -0,0 +1,41 @@
+package acme
+
+import (
+	"errors"
+
+	"github.com/cloudflare/cfssl/certdb"
+)
+
+// ErrNotFound is returned by Store lookups that find nothing.
+var ErrNotFound = errors.New("acme: not found")
+
+// Store persists ACME accounts, orders and authorizations, on top of the
+// same certdb.Accessor every other issuance path (newcert, crl, the OCSP
+// refresher) already uses for certificates and OCSP records. A Store
+// implementation is expected to be a certdb/sql accessor with these
+// methods added alongside InsertOCSP and friends, instrumented the same
+// way via mensureQueryTime, rather than a separate store bolted on next
+// to it.
+type Store interface {
+	certdb.Accessor
+
+	InsertACMEAccount(Account) error
+	GetACMEAccount(id string) (Account, error)
+
+	InsertOrder(Order) error
+	GetOrder(id string) (Order, error)
+	UpdateOrder(Order) error
+
+	InsertAuthorization(Authorization) error
+	GetAuthorization(id string) (Authorization, error)
+	UpdateAuthorization(Authorization) error
+
+	// InsertCertificatePEM and GetCertificatePEM persist the PEM chain
+	// handleCert serves, keyed by the leaf's serial number.
+	InsertCertificatePEM(serial string, chainPEM []byte) error
+	GetCertificatePEM(serial string) ([]byte, error)
+	// GetOrderByCertificateSerial finds the order that produced the
+	// certificate with the given serial, so handleCert and
+	// handleRevokeCert can check the requesting account actually owns it.
+	GetOrderByCertificateSerial(serial string) (Order, error)
+}
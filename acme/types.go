@@ -0,0 +1,97 @@
+package acme
+
+import "time"
+
+// Directory lists the API's resource URLs, as required by RFC 8555 §7.1.1.
+type Directory struct {
+	NewNonce   string `json:"newNonce"`
+	NewAccount string `json:"newAccount"`
+	NewOrder   string `json:"newOrder"`
+	RevokeCert string `json:"revokeCert"`
+	KeyChange  string `json:"keyChange,omitempty"`
+	Meta       *Meta  `json:"meta,omitempty"`
+}
+
+// Meta carries optional directory metadata (RFC 8555 §7.1.1).
+type Meta struct {
+	TermsOfService          string   `json:"termsOfService,omitempty"`
+	ExternalAccountRequired bool     `json:"externalAccountRequired,omitempty"`
+	CAAIdentities           []string `json:"caaIdentities,omitempty"`
+}
+
+// Identifier is an ACME identifier (RFC 8555 §9.7.7); "dns" is the only
+// type this package issues for.
+type Identifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// Account is a registered ACME account (RFC 8555 §7.1.2).
+type Account struct {
+	ID                     string   `json:"-"`
+	Status                 string   `json:"status"`
+	Contact                []string `json:"contact,omitempty"`
+	TermsOfServiceAgreed   bool     `json:"termsOfServiceAgreed,omitempty"`
+	JWK                    []byte   `json:"-"` // the account's public key, as a JWK
+	ExternalAccountBinding []byte   `json:"-"`
+	OrdersURL              string   `json:"orders,omitempty"`
+}
+
+// Order is an ACME order (RFC 8555 §7.1.3).
+type Order struct {
+	ID                string       `json:"-"`
+	AccountID         string       `json:"-"`
+	Status            string       `json:"status"`
+	Expires           time.Time    `json:"expires,omitempty"`
+	Identifiers       []Identifier `json:"identifiers"`
+	NotBefore         time.Time    `json:"notBefore,omitempty"`
+	NotAfter          time.Time    `json:"notAfter,omitempty"`
+	AuthorizationURLs []string     `json:"authorizations"`
+	FinalizeURL       string       `json:"finalize"`
+	CertificateURL    string       `json:"certificate,omitempty"`
+	CertificateSerial string       `json:"-"` // set once finalize issues a certificate; indexes Store's cert lookups
+}
+
+// Authorization is an ACME authorization (RFC 8555 §7.1.4).
+type Authorization struct {
+	ID         string      `json:"-"`
+	OrderID    string      `json:"-"`
+	Identifier Identifier  `json:"identifier"`
+	Status     string      `json:"status"`
+	Expires    time.Time   `json:"expires,omitempty"`
+	Challenges []Challenge `json:"challenges"`
+	Wildcard   bool        `json:"wildcard,omitempty"`
+}
+
+// Challenge is a single authorization challenge (RFC 8555 §8).
+type Challenge struct {
+	Type      string    `json:"type"`
+	URL       string    `json:"url"`
+	Status    string    `json:"status"`
+	Token     string    `json:"token"`
+	Validated time.Time `json:"validated,omitempty"`
+	Error     *Problem  `json:"error,omitempty"`
+}
+
+// Problem is an RFC 7807 problem document, used for ACME error
+// responses (RFC 8555 §6.7).
+type Problem struct {
+	Type   string `json:"type"`
+	Detail string `json:"detail"`
+	Status int    `json:"status,omitempty"`
+}
+
+const (
+	StatusPending     = "pending"
+	StatusProcessing  = "processing"
+	StatusValid       = "valid"
+	StatusInvalid     = "invalid"
+	StatusReady       = "ready"
+	StatusDeactivated = "deactivated"
+	StatusRevoked     = "revoked"
+)
+
+const (
+	ChallengeHTTP01 = "http-01"
+	ChallengeDNS01  = "dns-01"
+)
@@ -1,10 +1,13 @@
 package newcert
 
 import (
+	"context"
 	"crypto/md5"
+	"crypto/rand"
 	"crypto/sha1"
 	"crypto/sha256"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"encoding/pem"
@@ -19,16 +22,40 @@ import (
 	"github.com/cloudflare/cfssl/errors"
 	"github.com/cloudflare/cfssl/log"
 	"github.com/cloudflare/cfssl/ocsp"
+	"github.com/cloudflare/cfssl/ocsp/refresher"
 	"github.com/cloudflare/cfssl/signer"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	stdocsp "golang.org/x/crypto/ocsp"
+	pkcs12 "software.sslmate.com/src/go-pkcs12"
 )
 
+// tracer provides the spans for certificate issuance so that latency can
+// be sliced by tenant (profile/label). dbAccessor.InsertOCSP is not passed
+// ctx, so query spans recorded by certdb/sql are not children of these
+// spans.
+var tracer = otel.Tracer("cfssl/api/newcert")
+
 const (
 	// CSRNoHostMessage is used to alert the user to a certificate lacking a hosts field.
 	CSRNoHostMessage = `This certificate lacks a "hosts" field. This makes it unsuitable for
 websites. For more information see the Baseline Requirements for the Issuance and Management
 of Publicly-Trusted Certificates, v.1.1.6, from the CA/Browser Forum (https://cabforum.org);
 specifically, section 10.2.3 ("Information Requirements").`
+
+	// BundleFormatPEM is the default response mode: PEM-encoded key,
+	// CSR and certificate fields.
+	BundleFormatPEM = "pem"
+	// BundleFormatPKCS12 additionally returns a password-protected
+	// PKCS#12 bundle containing the leaf key/certificate and the
+	// intermediate chain.
+	BundleFormatPKCS12 = "pkcs12"
+
+	// minBundlePasswordLength is the minimum length enforced on
+	// BundlePassword when BundleFormat is "pkcs12".
+	minBundlePasswordLength = 8
 )
 
 // Validator is a type of function that contains the logic for validating
@@ -40,6 +67,14 @@ type newCertRequest struct {
 	Profile string                  `json:"profile"`
 	Label   string                  `json:"label"`
 	Bundle  bool                    `json:"bundle"`
+
+	// BundleFormat selects the response encoding: BundleFormatPEM
+	// (default) or BundleFormatPKCS12.
+	BundleFormat string `json:"bundle_format,omitempty"`
+	// BundlePassword encrypts the PKCS#12 bundle. Required, and must be
+	// at least minBundlePasswordLength characters, when BundleFormat is
+	// BundleFormatPKCS12.
+	BundlePassword string `json:"bundle_password,omitempty"`
 }
 
 // Sum contains digests for a certificate or certificate request.
@@ -53,6 +88,7 @@ type options struct {
 	ocspSigner    ocsp.Signer
 	caBundleFile  string
 	intBundleFile string
+	refresherCfg  *refresher.Config
 }
 
 // Opt is a functional option for configuring a new Handler.
@@ -65,6 +101,16 @@ func WithOCSPSigner(signer ocsp.Signer) Opt {
 	}
 }
 
+// WithOCSPRefresher starts a background refresher.Refresher, using the
+// Handler's OCSP signer and the signer's certdb accessor, that keeps
+// stored OCSP responses from going stale between issuances. It is a
+// no-op unless WithOCSPSigner is also supplied.
+func WithOCSPRefresher(cfg refresher.Config) Opt {
+	return func(o *options) {
+		o.refresherCfg = &cfg
+	}
+}
+
 // WithBundler sets the CA and intermediate bundle files for the Handler.
 func WithBundler(caBundleFile, intBundleFile string) Opt {
 	return func(o *options) {
@@ -81,6 +127,19 @@ type Handler struct {
 	bundler    *bundler.Bundler
 	signer     signer.Signer
 	ocspSigner ocsp.Signer
+
+	// refresher is non-nil when WithOCSPRefresher was supplied; Close
+	// stops its background goroutine.
+	refresher *refresher.Refresher
+}
+
+// Close stops the Handler's background OCSP refresher, if one was
+// started via WithOCSPRefresher. It is safe to call on a Handler that
+// never started one.
+func (h *Handler) Close() {
+	if h.refresher != nil {
+		h.refresher.Stop()
+	}
 }
 
 // NewHandler creates a new Handler for generating certificates directly
@@ -104,6 +163,11 @@ func NewHandler(validator Validator, signer signer.Signer, opts ...Opt) (http.Ha
 	hdl.bundler = bundler
 	hdl.ocspSigner = options.ocspSigner
 
+	if options.refresherCfg != nil && hdl.ocspSigner != nil {
+		hdl.refresher = refresher.New(signer.GetDBAccessor(), hdl.ocspSigner, *options.refresherCfg)
+		hdl.refresher.Start(context.Background())
+	}
+
 	return api.HTTPHandler{
 		Handler: hdl,
 		Methods: []string{"POST"},
@@ -114,36 +178,61 @@ func NewHandler(validator Validator, signer signer.Signer, opts ...Opt) (http.Ha
 func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) error {
 	log.Info("request for csr (with ocsp support)")
 
+	ctx, span := tracer.Start(r.Context(), "newcert.Handle")
+	defer span.End()
+
 	newCert := newCertRequest{}
 	newCert.Request = csr.New()
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		log.Warningf("failed to read request body: %v", err)
-		return errors.NewBadRequest(err)
+		return recordErr(span, errors.NewBadRequest(err))
 	}
 
 	err = json.Unmarshal(body, &newCert)
 	if err != nil {
 		log.Warningf("failed to unmarshal request: %v", err)
-		return errors.NewBadRequest(err)
+		return recordErr(span, errors.NewBadRequest(err))
 	}
 
 	if newCert.Request == nil {
 		log.Warning("empty request received")
-		return errors.NewBadRequestString("missing request section")
+		return recordErr(span, errors.NewBadRequestString("missing request section"))
 	}
 
 	if newCert.Request.CA != nil {
 		log.Warningf("request received with CA section")
-		return errors.NewBadRequestString("ca section only permitted in initca")
+		return recordErr(span, errors.NewBadRequestString("ca section only permitted in initca"))
 	}
 
+	span.SetAttributes(
+		attribute.String("cfssl.profile", newCert.Profile),
+		attribute.String("cfssl.label", newCert.Label),
+	)
+	if len(newCert.Request.Hosts) > 0 {
+		span.SetAttributes(attribute.String("cfssl.cn", newCert.Request.CN))
+	}
+
+	_, csrSpan := tracer.Start(ctx, "newcert.generateCSR")
 	csr, key, err := h.csrGen.ProcessRequest(newCert.Request)
+	csrSpan.End()
 	if err != nil {
 		log.Warningf("failed to process CSR: %v", err)
 		// The validator returns a *cfssl/errors.HttpError
-		return err
+		return recordErr(span, err)
+	}
+
+	if newCert.BundleFormat == BundleFormatPKCS12 {
+		if len(key) == 0 {
+			log.Warning("pkcs12 bundle requested for a pre-generated CSR")
+			return recordErr(span, errors.NewBadRequestString(
+				"pkcs12 bundle format requires a server-generated private key"))
+		}
+		if len(newCert.BundlePassword) < minBundlePasswordLength {
+			return recordErr(span, errors.NewBadRequestString(
+				fmt.Sprintf("bundle_password must be at least %d characters", minBundlePasswordLength)))
+		}
 	}
 
 	signReq := signer.SignRequest{
@@ -152,49 +241,57 @@ func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) error {
 		Label:   newCert.Label,
 	}
 
+	signCtx, signSpan := tracer.Start(ctx, "signer.Sign")
 	certBytes, err := h.signer.Sign(signReq)
+	signSpan.End()
 	if err != nil {
 		log.Warningf("failed to sign request: %v", err)
-		return err
+		return recordErr(span, err)
 	}
 
 	reqSum, err := computeSum(csr)
 	if err != nil {
-		return errors.NewBadRequest(err)
+		return recordErr(span, errors.NewBadRequest(err))
 	}
 
 	certSum, err := computeSum(certBytes)
 	if err != nil {
-		return errors.NewBadRequest(err)
+		return recordErr(span, errors.NewBadRequest(err))
 	}
 
+	_, bundleSpan := tracer.Start(signCtx, "bundler.BundleFromPEMorDER")
 	bundle, err := h.bundler.BundleFromPEMorDER(certBytes, nil, bundler.Optimal, "")
+	bundleSpan.End()
 	if err != nil {
-		return err
+		return recordErr(span, err)
 	}
 
 	if bundle == nil {
 		log.Critical("failed to bundle certificate")
-		return fmt.Errorf("failed to bundle certificate")
+		return recordErr(span, fmt.Errorf("failed to bundle certificate"))
 	}
 
+	span.SetAttributes(attribute.String("cfssl.serial", bundle.Cert.SerialNumber.String()))
+
 	if h.ocspSigner != nil {
+		ocspCtx, ocspSpan := tracer.Start(ctx, "ocspSigner.Sign")
 		ocspReq := ocsp.SignRequest{
 			Certificate: bundle.Cert,
 			Status:      "good",
 		}
 
 		ocspResponse, err := h.ocspSigner.Sign(ocspReq)
+		ocspSpan.End()
 		if err != nil {
 			log.Critical("Unable to sign OCSP response: ", err)
-			return err
+			return recordErr(span, err)
 		}
 
 		// We parse the OCSP response in order to get the next
 		// update time/expiry time
 		ocspParsed, err := stdocsp.ParseResponse(ocspResponse, nil)
 		if err != nil {
-			return err
+			return recordErr(span, err)
 		}
 
 		ocspRecord := certdb.OCSPRecord{
@@ -204,25 +301,43 @@ func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) error {
 			Expiry: ocspParsed.NextUpdate,
 		}
 
+		_, insertSpan := tracer.Start(ocspCtx, "dbAccessor.InsertOCSP")
 		dbAccessor := h.signer.GetDBAccessor()
-		if err := dbAccessor.InsertOCSP(ocspRecord); err != nil {
+		err = dbAccessor.InsertOCSP(ocspRecord)
+		insertSpan.End()
+		if err != nil {
 			log.Critical("Unable to insert OCSP response: ", err)
-			return err
+			return recordErr(span, err)
 		}
 	}
 
+	sums := map[string]Sum{
+		"certificate_request": reqSum,
+		"certificate":         certSum,
+	}
+
 	result := map[string]interface{}{
 		"private_key":         string(key),
 		"certificate_request": string(csr),
 		"certificate":         string(certBytes),
 		"serial_number":       bundle.Cert.SerialNumber.String(),
 		"expiration":          bundle.Expires.Unix(),
-		"sums": map[string]Sum{
-			"certificate_request": reqSum,
-			"certificate":         certSum,
-		},
 	}
 
+	if newCert.BundleFormat == BundleFormatPKCS12 {
+		_, p12Span := tracer.Start(ctx, "newcert.encodePKCS12")
+		p12, err := h.encodePKCS12(key, bundle, newCert.BundlePassword)
+		p12Span.End()
+		if err != nil {
+			return recordErr(span, err)
+		}
+
+		result["pkcs12"] = base64.StdEncoding.EncodeToString(p12)
+		sums["pkcs12"] = computeRawSum(p12)
+	}
+
+	result["sums"] = sums
+
 	if len(newCert.Request.Hosts) == 0 {
 		return api.SendResponseWithMessage(w, result, CSRNoHostMessage,
 			errors.New(errors.PolicyError, errors.InvalidRequest).ErrorCode)
@@ -231,6 +346,65 @@ func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) error {
 	return api.SendResponse(w, result)
 }
 
+// recordErr marks span as failed and returns err unchanged, so call sites
+// can write "return recordErr(span, err)".
+func recordErr(span trace.Span, err error) error {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	return err
+}
+
+// encodePKCS12 builds a password-protected PKCS#12 bundle containing
+// keyPEM, the leaf certificate from bundle and its intermediate chain.
+func (h *Handler) encodePKCS12(keyPEM []byte, bundle *bundler.Bundle, password string) ([]byte, error) {
+	priv, err := parsePrivateKeyPEM(keyPEM)
+	if err != nil {
+		return nil, errors.NewBadRequest(err)
+	}
+
+	var caCerts []*x509.Certificate
+	if len(bundle.Chain) > 1 {
+		// bundle.Chain is leaf-first; the leaf is passed separately below.
+		caCerts = bundle.Chain[1:]
+	}
+
+	return pkcs12.Encode(rand.Reader, priv, bundle.Cert, caCerts, password)
+}
+
+// parsePrivateKeyPEM parses a PEM-encoded private key produced by
+// csr.Generator, trying the encodings cfssl is known to emit.
+func parsePrivateKeyPEM(in []byte) (interface{}, error) {
+	p, _ := pem.Decode(in)
+	if p == nil {
+		return nil, fmt.Errorf("not a PEM-encoded private key")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(p.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(p.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(p.Bytes); err == nil {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unrecognized private key encoding")
+}
+
+// computeRawSum digests data directly, for artifacts such as a PKCS#12
+// bundle that aren't themselves PEM-encoded.
+func computeRawSum(data []byte) Sum {
+	md5Sum := md5.Sum(data)
+	sha1Sum := sha1.Sum(data)
+	sha256Sum := sha256.Sum256(data)
+	return Sum{
+		MD5:    fmt.Sprintf("%X", md5Sum[:]),
+		SHA1:   fmt.Sprintf("%X", sha1Sum[:]),
+		SHA256: fmt.Sprintf("%X", sha256Sum[:]),
+	}
+}
+
 func computeSum(in []byte) (sum Sum, err error) {
 	var data []byte
 	p, _ := pem.Decode(in)
@@ -3,6 +3,7 @@ package dbmetrics
 import (
 	"database/sql"
 
+	"github.com/cloudflare/cfssl/metrics"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -26,7 +27,13 @@ type StatsCollector struct {
 	closedMaxLifetimeDesc *prometheus.Desc
 }
 
+// NewStatsCollector returns a prometheus.Collector for s's connection
+// pool stats, and also registers s with the shared metrics.Default
+// registry so the same stats are observable as OTel gauges when that
+// backend is enabled.
 func NewStatsCollector(s Statser, dbName string) *StatsCollector {
+	metrics.Default.ObserveStatser(dbName, s)
+
 	labels := prometheus.Labels{"db_name": dbName}
 	return &StatsCollector{
 		s: s,
@@ -2,31 +2,55 @@ package sql
 
 import (
 	"context"
+	"sync"
 	"time"
 
+	"github.com/cloudflare/cfssl/metrics"
 	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/metric"
 )
 
-// meter can be a global/package variable.
-var meter = otel.Meter("cfssl/certdb")
+// tracer is used to create a child span around each query so that the
+// timing histogram below can be correlated with the request that
+// triggered it.
+var tracer = otel.Tracer("cfssl/certdb")
 
-var queryHistogram, _ = meter.Int64Histogram(
-	"query_timing",
-	metric.WithDescription("The time it takes to query the database"),
-	metric.WithUnit("milliseconds"),
+// queryTimingOnce builds queryTiming against metrics.Default on first use
+// rather than at package-init time, so a call to metrics.SetDefault made
+// during application startup (before the first query) is still honored;
+// building it as a package-level var initializer would capture whatever
+// metrics.Default was at import time, before any application code -
+// including SetDefault - had a chance to run.
+var (
+	queryTimingOnce sync.Once
+	queryTimingHist *metrics.Histogram
 )
 
-func mensureQueryTime(operation string) func() {
+func queryTiming() *metrics.Histogram {
+	queryTimingOnce.Do(func() {
+		queryTimingHist = metrics.Default.NewDurationHistogram(
+			"query_timing", "certdb_query_timing_milliseconds",
+			"The time it takes to query the database",
+			"operation",
+		)
+	})
+	return queryTimingHist
+}
+
+// mensureQueryTime starts a child span for operation under the span
+// carried by ctx and returns a func that, when called, ends the span and
+// records the elapsed time against queryTiming. ctx must carry the
+// caller's span so that the recorded span/histogram entry is correlated
+// with the originating HTTP request. No accessor method in this package
+// calls it yet -- certdb/sql has no concrete certdb.Accessor
+// implementation in this tree for it to instrument -- so until one
+// exists, wrapping a query with mensureQueryTime is the caller's
+// responsibility, the same way acme.Handler.storeOp wraps Store calls.
+func mensureQueryTime(ctx context.Context, operation string) func() {
+	ctx, span := tracer.Start(ctx, "sql."+operation)
 	start := time.Now()
 	return func() {
 		elapsed := time.Since(start)
-
-		queryHistogram.Record(
-			context.Background(),
-			elapsed.Milliseconds(),
-			metric.WithAttributes(attribute.String("operation", operation)),
-		)
+		span.End()
+		queryTiming().Record(ctx, elapsed.Milliseconds(), operation)
 	}
 }
@@ -0,0 +1,34 @@
+package sql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cloudflare/cfssl/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestQueryTimingHonorsSetDefaultBeforeFirstUse must be the first thing
+// in this package to call mensureQueryTime: queryTiming resolves
+// metrics.Default exactly once (see queryTimingOnce), so this exercises
+// the scenario the SetDefault doc comment promises -- overriding Default
+// before the query-timing instrument is first built actually takes
+// effect, unlike when it was built from a package-level var initializer.
+func TestQueryTimingHonorsSetDefaultBeforeFirstUse(t *testing.T) {
+	metrics.SetDefault(metrics.NewRegistry(metrics.Config{
+		PrometheusEnabled: true,
+		OTelEnabled:       false,
+	}))
+
+	done := mensureQueryTime(context.Background(), "test-operation")
+	done()
+
+	count, err := testutil.GatherAndCount(prometheus.DefaultGatherer, "certdb_query_timing_milliseconds")
+	if err != nil {
+		t.Fatalf("GatherAndCount: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("certdb_query_timing_milliseconds series count = %d, want 1", count)
+	}
+}
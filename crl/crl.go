@@ -0,0 +1,340 @@
+// Package crl runs a background worker that periodically builds sharded
+// CRLs (one shard per issuer Authority Key Identifier) from the
+// certificates recorded in certdb, and serves the most recent shard for
+// each issuer over HTTP.
+package crl
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/cloudflare/cfssl/certdb"
+	"github.com/cloudflare/cfssl/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+var tracer = otel.Tracer("cfssl/crl")
+
+// Signer produces a signed CRL for the issuer that owns aki. It is
+// implemented by whatever holds the CA's private key for that issuer;
+// in a single-CA deployment this is typically a thin wrapper around the
+// same signer.Signer used by newcert.NewHandler.
+type Signer interface {
+	SignCRL(aki string, template *x509.RevocationList) ([]byte, error)
+}
+
+// shard is the last CRL generated for a given issuer.
+type shard struct {
+	der        []byte
+	number     *big.Int
+	lastUpdate time.Time
+	nextUpdate time.Time
+}
+
+// NumberStore persists the last CRL number issued per issuer so that a
+// process restart can never reuse or regress one: x509.RevocationList.Number
+// must strictly increase for a given issuer (RFC 5280 §5.2.3), and clients
+// are entitled to reject a CRL whose number goes backwards.
+type NumberStore interface {
+	// LastNumber returns the last number saved for aki, or nil if none
+	// has been saved yet.
+	LastNumber(ctx context.Context, aki string) (*big.Int, error)
+	// SaveNumber records number as the last one issued for aki.
+	SaveNumber(ctx context.Context, aki string, number *big.Int) error
+}
+
+// Worker periodically regenerates and publishes CRL shards.
+type Worker struct {
+	dbAccessor certdb.Accessor
+	signer     Signer
+	publisher  Publisher
+
+	interval time.Duration
+	validity time.Duration
+	overlap  time.Duration
+
+	numberStore NumberStore
+
+	mu     sync.RWMutex
+	shards map[string]*shard // keyed by lowercase hex AKI
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Opt is a functional option for configuring a Worker.
+type Opt func(*Worker)
+
+// WithInterval sets how often the worker regenerates shards. Defaults to
+// one hour.
+func WithInterval(d time.Duration) Opt {
+	return func(w *Worker) { w.interval = d }
+}
+
+// WithValidity sets how long a generated shard remains valid (the gap
+// between ThisUpdate and NextUpdate). Defaults to 24 hours.
+func WithValidity(d time.Duration) Opt {
+	return func(w *Worker) { w.validity = d }
+}
+
+// WithOverlap sets how long before NextUpdate a shard is eligible to be
+// regenerated, so that a republished shard always overlaps the validity
+// window of the one it replaces. Defaults to one hour.
+func WithOverlap(d time.Duration) Opt {
+	return func(w *Worker) { w.overlap = d }
+}
+
+// WithNumberStore sets where CRL numbers are persisted across restarts.
+// If publisher also implements NumberStore (as FilePublisher does),
+// NewWorker already uses it by default; call this to use a different
+// store, e.g. one backed by certdb.
+func WithNumberStore(store NumberStore) Opt {
+	return func(w *Worker) { w.numberStore = store }
+}
+
+// NewWorker creates a Worker that reads revoked certificates from
+// dbAccessor, signs shards with signer and persists them via publisher.
+func NewWorker(dbAccessor certdb.Accessor, signer Signer, publisher Publisher, opts ...Opt) *Worker {
+	w := &Worker{
+		dbAccessor:  dbAccessor,
+		signer:      signer,
+		publisher:   publisher,
+		interval:    time.Hour,
+		validity:    24 * time.Hour,
+		overlap:     time.Hour,
+		shards:      make(map[string]*shard),
+		numberStore: newMemNumberStore(),
+	}
+	if store, ok := publisher.(NumberStore); ok {
+		w.numberStore = store
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Start launches the background generation loop. It returns immediately;
+// call Stop to shut the loop down.
+func (w *Worker) Start(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	w.cancel = cancel
+	w.done = make(chan struct{})
+
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		w.runCycle(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				w.runCycle(ctx)
+			}
+		}
+	}()
+}
+
+// Stop halts the background loop and waits for the in-flight cycle, if
+// any, to finish.
+func (w *Worker) Stop() {
+	if w.cancel == nil {
+		return
+	}
+	w.cancel()
+	<-w.done
+}
+
+// runCycle generates and publishes one shard per issuer that currently
+// has revoked, unexpired certificates on record and is due for refresh
+// (see dueForRefresh); other issuers keep their current shard.
+func (w *Worker) runCycle(ctx context.Context) {
+	ctx, span := tracer.Start(ctx, "crl.runCycle")
+	defer span.End()
+
+	records, err := w.dbAccessor.GetRevokedAndUnexpiredCertificates()
+	if err != nil {
+		log.Errorf("crl: failed to load revoked certificates: %v", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+
+	byAKI := make(map[string][]certdb.CertificateRecord)
+	for _, rec := range records {
+		byAKI[rec.AKI] = append(byAKI[rec.AKI], rec)
+	}
+
+	for aki, certs := range byAKI {
+		if !w.dueForRefresh(aki) {
+			continue
+		}
+		if err := w.generateAndPublish(ctx, aki, certs); err != nil {
+			log.Errorf("crl: failed to generate shard for AKI %s: %v", aki, err)
+			publishFailuresTotal.WithLabelValues(aki).Inc()
+		}
+	}
+}
+
+// dueForRefresh reports whether the shard for aki has no prior generation
+// or is within the overlap window of its NextUpdate.
+func (w *Worker) dueForRefresh(aki string) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	s, ok := w.shards[aki]
+	if !ok {
+		return true
+	}
+	return time.Now().After(s.nextUpdate.Add(-w.overlap))
+}
+
+func (w *Worker) generateAndPublish(ctx context.Context, aki string, certs []certdb.CertificateRecord) error {
+	ctx, span := tracer.Start(ctx, "crl.generateAndPublish")
+	span.SetAttributes(attribute.String("cfssl.aki", aki), attribute.Int("cfssl.revoked_count", len(certs)))
+	defer span.End()
+
+	number, err := w.nextNumber(ctx, aki)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	now := time.Now()
+
+	revoked := make([]pkix.RevokedCertificate, 0, len(certs))
+	for _, c := range certs {
+		serial, ok := new(big.Int).SetString(c.Serial, 10)
+		if !ok {
+			log.Warningf("crl: skipping record with unparsable serial %q", c.Serial)
+			continue
+		}
+		revoked = append(revoked, pkix.RevokedCertificate{
+			SerialNumber:   serial,
+			RevocationTime: c.RevokedAt,
+		})
+	}
+
+	template := &x509.RevocationList{
+		Number:              number,
+		RevokedCertificates: revoked,
+		ThisUpdate:          now,
+		NextUpdate:          now.Add(w.validity),
+		// SignatureAlgorithm left at its zero value
+		// (x509.UnknownSignatureAlgorithm) so the signer picks the
+		// algorithm appropriate for its own key, the same way
+		// x509.CreateRevocationList does -- hardcoding SHA256WithRSA
+		// here would break any ECDSA-signed CA.
+	}
+
+	der, err := w.signer.SignCRL(aki, template)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("crl: failed to sign shard for %s: %w", aki, err)
+	}
+
+	if err := w.publisher.Publish(ctx, aki, der); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("crl: failed to publish shard for %s: %w", aki, err)
+	}
+
+	w.mu.Lock()
+	w.shards[aki] = &shard{
+		der:        der,
+		number:     number,
+		lastUpdate: template.ThisUpdate,
+		nextUpdate: template.NextUpdate,
+	}
+	w.mu.Unlock()
+
+	generatedTotal.WithLabelValues(aki).Inc()
+	sizeBytes.WithLabelValues(aki).Set(float64(len(der)))
+
+	return nil
+}
+
+// nextNumber returns the next monotonically increasing CRL number for
+// aki, starting at 1, persisting it via w.numberStore before returning so
+// a crash between here and publish never hands out the same number
+// twice.
+func (w *Worker) nextNumber(ctx context.Context, aki string) (*big.Int, error) {
+	last, err := w.numberStore.LastNumber(ctx, aki)
+	if err != nil {
+		return nil, fmt.Errorf("crl: failed to load last CRL number for %s: %w", aki, err)
+	}
+	if last == nil {
+		last = big.NewInt(0)
+	}
+	n := new(big.Int).Add(last, big.NewInt(1))
+	if err := w.numberStore.SaveNumber(ctx, aki, n); err != nil {
+		return nil, fmt.Errorf("crl: failed to persist CRL number for %s: %w", aki, err)
+	}
+	return n, nil
+}
+
+// memNumberStore is the default NumberStore: it keeps numbers in memory
+// only, so a process restart resets to 1. NewWorker only falls back to it
+// when publisher doesn't already implement NumberStore; production
+// deployments should supply WithNumberStore backed by certdb or another
+// durable store to get real cross-restart monotonicity.
+type memNumberStore struct {
+	mu      sync.Mutex
+	numbers map[string]*big.Int
+}
+
+func newMemNumberStore() *memNumberStore {
+	return &memNumberStore{numbers: make(map[string]*big.Int)}
+}
+
+func (s *memNumberStore) LastNumber(_ context.Context, aki string) (*big.Int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, ok := s.numbers[aki]
+	if !ok {
+		return nil, nil
+	}
+	return new(big.Int).Set(n), nil
+}
+
+func (s *memNumberStore) SaveNumber(_ context.Context, aki string, number *big.Int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.numbers[aki] = new(big.Int).Set(number)
+	return nil
+}
+
+// shardFor returns the most recently generated DER-encoded shard for aki,
+// if any.
+func (w *Worker) shardFor(aki string) ([]byte, time.Time, bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	s, ok := w.shards[aki]
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	return s.der, s.lastUpdate, true
+}
+
+// normalizeAKI lower-cases a hex-encoded AKI so map lookups are
+// case-insensitive regardless of how the caller formatted it.
+func normalizeAKI(aki string) (string, error) {
+	decoded, err := hex.DecodeString(aki)
+	if err != nil {
+		return "", fmt.Errorf("crl: invalid AKI %q: %w", aki, err)
+	}
+	return hex.EncodeToString(decoded), nil
+}
@@ -0,0 +1,60 @@
+package crl
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Handler serves the most recently generated CRL shard for each issuer at
+// /crl/{aki}.crl.
+type Handler struct {
+	worker *Worker
+}
+
+// NewHandler returns an http.Handler that serves shards generated by w.
+func NewHandler(w *Worker) *Handler {
+	return &Handler{worker: w}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	aki := akiFromPath(r.URL.Path)
+	if aki == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	normalized, err := normalizeAKI(aki)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	der, lastUpdate, ok := h.worker.shardFor(normalized)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pkix-crl")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.worker.overlap.Seconds())))
+	w.Header().Set("Last-Modified", lastUpdate.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusOK)
+	w.Write(der)
+}
+
+// akiFromPath extracts the AKI from a request path of the form
+// "/crl/{aki}.crl".
+func akiFromPath(path string) string {
+	path = strings.TrimPrefix(path, "/crl/")
+	path = strings.TrimSuffix(path, ".crl")
+	if strings.ContainsAny(path, "/") {
+		return ""
+	}
+	return path
+}
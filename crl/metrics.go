@@ -0,0 +1,27 @@
+package crl
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Metrics mirrors the certdb/dbmetrics pattern: a small set of
+// package-level collectors registered once and updated from the
+// generation/publish path.
+var (
+	generatedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "crl_generated_total",
+		Help: "Total number of CRLs generated, by issuer AKI",
+	}, []string{"aki"})
+
+	publishFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "crl_publish_failures_total",
+		Help: "Total number of failed CRL publish attempts, by issuer AKI",
+	}, []string{"aki"})
+
+	sizeBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "crl_size_bytes",
+		Help: "Size in bytes of the most recently generated CRL, by issuer AKI",
+	}, []string{"aki"})
+)
+
+func init() {
+	prometheus.MustRegister(generatedTotal, publishFailuresTotal, sizeBytes)
+}
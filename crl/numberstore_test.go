@@ -0,0 +1,58 @@
+package crl
+
+import (
+	"context"
+	"math/big"
+	"testing"
+)
+
+func TestMemNumberStoreMonotonic(t *testing.T) {
+	store := newMemNumberStore()
+	ctx := context.Background()
+	w := &Worker{numberStore: store}
+
+	for i := int64(1); i <= 3; i++ {
+		n, err := w.nextNumber(ctx, "aki-a")
+		if err != nil {
+			t.Fatalf("nextNumber: %v", err)
+		}
+		if n.Cmp(big.NewInt(i)) != 0 {
+			t.Fatalf("nextNumber call %d = %s, want %d", i, n, i)
+		}
+	}
+
+	// A second issuer's numbering must not be affected by the first.
+	n, err := w.nextNumber(ctx, "aki-b")
+	if err != nil {
+		t.Fatalf("nextNumber: %v", err)
+	}
+	if n.Cmp(big.NewInt(1)) != 0 {
+		t.Fatalf("nextNumber for a fresh AKI = %s, want 1", n)
+	}
+}
+
+func TestFilePublisherNumberStoreSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	// First "process": issue three numbers for an issuer.
+	first := NewFilePublisher(dir)
+	w1 := &Worker{numberStore: first}
+	for i := 0; i < 3; i++ {
+		if _, err := w1.nextNumber(ctx, "deadbeef"); err != nil {
+			t.Fatalf("nextNumber: %v", err)
+		}
+	}
+
+	// A brand-new Worker/FilePublisher pointed at the same directory must
+	// pick up where the last process left off, not reset to 1.
+	second := NewFilePublisher(dir)
+	w2 := &Worker{numberStore: second}
+	n, err := w2.nextNumber(ctx, "deadbeef")
+	if err != nil {
+		t.Fatalf("nextNumber after restart: %v", err)
+	}
+	if n.Cmp(big.NewInt(4)) != 0 {
+		t.Fatalf("nextNumber after restart = %s, want 4 (never regress)", n)
+	}
+}
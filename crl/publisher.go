@@ -0,0 +1,87 @@
+package crl
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Publisher persists a freshly generated CRL so it can be served to
+// clients. Worker calls Publish from its background goroutine, one shard
+// at a time.
+type Publisher interface {
+	// Publish stores der, the DER-encoded CRL for the issuer identified
+	// by aki (lowercase hex-encoded Authority Key Identifier).
+	Publish(ctx context.Context, aki string, der []byte) error
+}
+
+// FilePublisher writes each shard to <dir>/<aki>.crl. It is intended for
+// single-node deployments and local development; production deployments
+// should implement Publisher against an S3-compatible object store.
+type FilePublisher struct {
+	Dir string
+}
+
+// NewFilePublisher creates a FilePublisher that writes CRL shards under dir.
+func NewFilePublisher(dir string) *FilePublisher {
+	return &FilePublisher{Dir: dir}
+}
+
+// Publish implements Publisher.
+func (p *FilePublisher) Publish(_ context.Context, aki string, der []byte) error {
+	if err := os.MkdirAll(p.Dir, 0755); err != nil {
+		return fmt.Errorf("crl: failed to create output directory: %w", err)
+	}
+
+	path := filepath.Join(p.Dir, aki+".crl")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, der, 0644); err != nil {
+		return fmt.Errorf("crl: failed to write shard for %s: %w", aki, err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("crl: failed to finalize shard for %s: %w", aki, err)
+	}
+
+	return nil
+}
+
+// LastNumber implements NumberStore by reading the sidecar file written
+// by SaveNumber, so a Worker using FilePublisher gets durable CRL number
+// monotonicity across restarts for free.
+func (p *FilePublisher) LastNumber(_ context.Context, aki string) (*big.Int, error) {
+	data, err := os.ReadFile(p.numberPath(aki))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("crl: failed to read CRL number for %s: %w", aki, err)
+	}
+
+	n, ok := new(big.Int).SetString(strings.TrimSpace(string(data)), 10)
+	if !ok {
+		return nil, fmt.Errorf("crl: corrupt CRL number file for %s", aki)
+	}
+	return n, nil
+}
+
+// SaveNumber implements NumberStore.
+func (p *FilePublisher) SaveNumber(_ context.Context, aki string, number *big.Int) error {
+	if err := os.MkdirAll(p.Dir, 0755); err != nil {
+		return fmt.Errorf("crl: failed to create output directory: %w", err)
+	}
+
+	path := p.numberPath(aki)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(number.String()), 0644); err != nil {
+		return fmt.Errorf("crl: failed to write CRL number for %s: %w", aki, err)
+	}
+	return os.Rename(tmp, path)
+}
+
+func (p *FilePublisher) numberPath(aki string) string {
+	return filepath.Join(p.Dir, aki+".crlnum")
+}
@@ -0,0 +1,206 @@
+// Package metrics unifies the two metrics backends cfssl already speaks
+// independently — certdb/dbmetrics publishes connection-pool stats as
+// Prometheus gauges, while certdb/sql publishes query timing as an OTel
+// histogram. Registry drives both (or either) from the same call site, so
+// a single Statser or duration measurement shows up in whichever
+// backend(s) an operator has enabled.
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// meterName is the OTel meter namespace used for every instrument this
+// package creates; it is read from the same MeterProvider that
+// otel.Setup installs globally.
+const meterName = "cfssl/metrics"
+
+// Statser is implemented by anything that exposes database/sql
+// connection-pool statistics. It mirrors certdb/dbmetrics.Statser; it is
+// redeclared here, rather than imported, so this package has no
+// dependency on certdb/dbmetrics (which depends on this package).
+type Statser interface {
+	Stats() sql.DBStats
+}
+
+// Config controls which backend(s) a Registry drives.
+type Config struct {
+	// PrometheusEnabled publishes metrics as Prometheus collectors.
+	// Default's config takes it from METRICS_PROMETHEUS_ENABLED, true
+	// when unset; the zero Config leaves it false.
+	PrometheusEnabled bool
+	// OTelEnabled publishes metrics as OTel instruments against the
+	// meter configured by otel.Setup. Default's config takes it from
+	// METRICS_OTEL_ENABLED, true when unset; the zero Config leaves it
+	// false.
+	OTelEnabled bool
+}
+
+func configFromEnv() Config {
+	return Config{
+		PrometheusEnabled: boolEnv("METRICS_PROMETHEUS_ENABLED", true),
+		OTelEnabled:       boolEnv("METRICS_OTEL_ENABLED", true),
+	}
+}
+
+func boolEnv(name string, def bool) bool {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// Registry drives a set of instruments across the enabled backends.
+type Registry struct {
+	cfg   Config
+	meter metric.Meter
+
+	mu         sync.Mutex
+	statsers   []statserEntry
+	gaugesOnce sync.Once
+}
+
+type statserEntry struct {
+	dbName string
+	s      Statser
+}
+
+// NewRegistry creates a Registry driven by cfg.
+func NewRegistry(cfg Config) *Registry {
+	r := &Registry{cfg: cfg}
+	if cfg.OTelEnabled {
+		r.meter = otel.Meter(meterName)
+	}
+	return r
+}
+
+// Default is the process-wide Registry used by the certdb/dbmetrics and
+// certdb/sql shims, configured from the METRICS_*_ENABLED environment
+// variables. Call SetDefault before those packages' instruments are
+// first used to override it (e.g. from tests). Note this means before
+// first use, not merely before `go build`/init: instruments built as
+// package-level vars off Default (rather than resolved lazily) capture
+// whatever Default was at import time, which SetDefault can no longer
+// reach.
+var Default = NewRegistry(configFromEnv())
+
+// SetDefault replaces Default. It is not safe to call concurrently with
+// use of the previous Default.
+func SetDefault(r *Registry) {
+	Default = r
+}
+
+// Histogram records a duration-style measurement through every enabled
+// backend.
+type Histogram struct {
+	label    string
+	otelHist metric.Int64Histogram
+	promHist *prometheus.HistogramVec
+}
+
+// NewDurationHistogram creates a Histogram named otelName in the OTel
+// backend and promName in the Prometheus backend (as a HistogramVec with
+// a single label named labelName), honoring the Registry's enabled
+// backends.
+func (r *Registry) NewDurationHistogram(otelName, promName, description, labelName string) *Histogram {
+	h := &Histogram{label: labelName}
+
+	if r.cfg.OTelEnabled && r.meter != nil {
+		h.otelHist, _ = r.meter.Int64Histogram(
+			otelName,
+			metric.WithDescription(description),
+			metric.WithUnit("milliseconds"),
+		)
+	}
+
+	if r.cfg.PrometheusEnabled {
+		h.promHist = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: promName,
+			Help: description,
+		}, []string{labelName})
+		prometheus.MustRegister(h.promHist)
+	}
+
+	return h
+}
+
+// Record reports an elapsed-time measurement, in milliseconds, under
+// label on every enabled backend.
+func (h *Histogram) Record(ctx context.Context, ms int64, label string) {
+	if h.otelHist != nil {
+		h.otelHist.Record(ctx, ms, metric.WithAttributes(attribute.String(h.label, label)))
+	}
+	if h.promHist != nil {
+		h.promHist.WithLabelValues(label).Observe(float64(ms))
+	}
+}
+
+// ObserveStatser registers s's connection-pool stats, labeled by dbName,
+// as OTel observable gauges (db_open_connections, db_in_use_connections,
+// db_max_open_connections, db_idle_connections). Prometheus publishing of
+// the same stats continues to go through
+// certdb/dbmetrics.StatsCollector, which is already pull-based; this
+// method is a no-op if the Registry's OTel backend is disabled.
+func (r *Registry) ObserveStatser(dbName string, s Statser) {
+	if !r.cfg.OTelEnabled || r.meter == nil {
+		return
+	}
+
+	r.mu.Lock()
+	r.statsers = append(r.statsers, statserEntry{dbName: dbName, s: s})
+	r.mu.Unlock()
+
+	r.gaugesOnce.Do(r.registerStatserGauges)
+}
+
+func (r *Registry) registerStatserGauges() {
+	openGauge, _ := r.meter.Int64ObservableGauge(
+		"db_open_connections",
+		metric.WithDescription("Number of established connections to the database"),
+	)
+	inUseGauge, _ := r.meter.Int64ObservableGauge(
+		"db_in_use_connections",
+		metric.WithDescription("Number of connections currently in use"),
+	)
+	maxOpenGauge, _ := r.meter.Int64ObservableGauge(
+		"db_max_open_connections",
+		metric.WithDescription("Maximum number of open connections to the database"),
+	)
+	idleGauge, _ := r.meter.Int64ObservableGauge(
+		"db_idle_connections",
+		metric.WithDescription("Number of idle connections"),
+	)
+
+	_, _ = r.meter.RegisterCallback(
+		func(_ context.Context, o metric.Observer) error {
+			r.mu.Lock()
+			entries := append([]statserEntry(nil), r.statsers...)
+			r.mu.Unlock()
+
+			for _, e := range entries {
+				stats := e.s.Stats()
+				attrs := metric.WithAttributes(attribute.String("db_name", e.dbName))
+				o.ObserveInt64(openGauge, int64(stats.OpenConnections), attrs)
+				o.ObserveInt64(inUseGauge, int64(stats.InUse), attrs)
+				o.ObserveInt64(maxOpenGauge, int64(stats.MaxOpenConnections), attrs)
+				o.ObserveInt64(idleGauge, int64(stats.Idle), attrs)
+			}
+			return nil
+		},
+		openGauge, inUseGauge, maxOpenGauge, idleGauge,
+	)
+}
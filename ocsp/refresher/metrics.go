@@ -0,0 +1,31 @@
+package refresher
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	refreshBatchSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name: "ocsp_refresh_batch_seconds",
+		Help: "Time taken to scan and re-sign a batch of expiring OCSP responses",
+	})
+
+	refreshErrorsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "ocsp_refresh_errors_total",
+		Help: "Total number of OCSP refresh errors: failed loads of the records to scan and records that failed to refresh",
+	})
+
+	recordsRefreshedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "ocsp_records_refreshed_total",
+		Help: "Total number of OCSP responses successfully re-signed",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(refreshBatchSeconds, refreshErrorsTotal, recordsRefreshedTotal)
+}
+
+// observeBatchDuration starts a timer and returns a func that records the
+// elapsed time in the ocsp_refresh_batch_seconds histogram when called.
+func observeBatchDuration() func() {
+	timer := prometheus.NewTimer(refreshBatchSeconds)
+	return func() { timer.ObserveDuration() }
+}
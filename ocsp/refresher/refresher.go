@@ -0,0 +1,197 @@
+// Package refresher runs a background worker that keeps stored OCSP
+// responses fresh. newcert.Handler signs and stores an OCSP response once
+// at issuance; without this worker, responses served straight from
+// certdb go stale once their NextUpdate passes.
+package refresher
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/cloudflare/cfssl/certdb"
+	"github.com/cloudflare/cfssl/log"
+	"github.com/cloudflare/cfssl/ocsp"
+	stdocsp "golang.org/x/crypto/ocsp"
+)
+
+const (
+	defaultWindow    = 24 * time.Hour
+	defaultBatchSize = 100
+	defaultInterval  = 5 * time.Minute
+	jitterFraction   = 0.1
+)
+
+// Config configures a Refresher.
+type Config struct {
+	// Window is how far before an OCSPRecord's Expiry the refresher will
+	// consider it due for re-signing. Defaults to 24h.
+	Window time.Duration
+	// BatchSize caps how many records are re-signed per tick. Defaults
+	// to 100.
+	BatchSize int
+	// Interval is the base period between scans; each tick is jittered
+	// by +/-10% to avoid thundering-herd re-signs across replicas.
+	// Defaults to 5m.
+	Interval time.Duration
+}
+
+func (c Config) withDefaults() Config {
+	if c.Window <= 0 {
+		c.Window = defaultWindow
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaultBatchSize
+	}
+	if c.Interval <= 0 {
+		c.Interval = defaultInterval
+	}
+	return c
+}
+
+// accessor is the subset of certdb.Accessor the refresher actually uses,
+// declared narrowly (rather than depending on the full certdb.Accessor)
+// so tests can supply a small fake instead of a complete accessor
+// implementation. Any certdb.Accessor satisfies it.
+type accessor interface {
+	GetUnexpiredOCSPs() ([]certdb.OCSPRecord, error)
+	GetCertificate(serial, aki string) ([]certdb.CertificateRecord, error)
+	UpsertOCSP(serial, aki, body string, expiry time.Time) error
+}
+
+// Refresher periodically re-signs OCSP responses that are close to
+// expiry so that certdb never serves a stale response.
+type Refresher struct {
+	dbAccessor accessor
+	signer     ocsp.Signer
+	cfg        Config
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New creates a Refresher that scans dbAccessor for expiring OCSPRecords
+// and re-signs them with signer.
+func New(dbAccessor certdb.Accessor, signer ocsp.Signer, cfg Config) *Refresher {
+	return &Refresher{
+		dbAccessor: dbAccessor,
+		signer:     signer,
+		cfg:        cfg.withDefaults(),
+	}
+}
+
+// Start launches the background scan loop. It returns immediately; call
+// Stop to shut the loop down.
+func (r *Refresher) Start(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	r.cancel = cancel
+	r.done = make(chan struct{})
+
+	go func() {
+		defer close(r.done)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(jittered(r.cfg.Interval)):
+				r.runScan(ctx)
+			}
+		}
+	}()
+}
+
+// Stop halts the background loop and waits for the in-flight scan, if
+// any, to finish.
+func (r *Refresher) Stop() {
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	<-r.done
+}
+
+// runScan re-signs every OCSPRecord due for refresh, up to BatchSize
+// records per call.
+func (r *Refresher) runScan(ctx context.Context) {
+	stop := observeBatchDuration()
+	defer stop()
+
+	cutoff := time.Now().Add(r.cfg.Window)
+	records, err := r.dbAccessor.GetUnexpiredOCSPs()
+	if err != nil {
+		log.Errorf("ocsp/refresher: failed to load OCSP records: %v", err)
+		refreshErrorsTotal.Inc()
+		return
+	}
+
+	refreshed := 0
+	for _, rec := range records {
+		if refreshed >= r.cfg.BatchSize {
+			break
+		}
+		if rec.Expiry.After(cutoff) {
+			continue
+		}
+		if err := r.refreshOne(ctx, rec); err != nil {
+			log.Errorf("ocsp/refresher: failed to refresh serial %s: %v", rec.Serial, err)
+			refreshErrorsTotal.Inc()
+			continue
+		}
+		refreshed++
+	}
+
+	recordsRefreshedTotal.Add(float64(refreshed))
+}
+
+func (r *Refresher) refreshOne(_ context.Context, rec certdb.OCSPRecord) error {
+	crs, err := r.dbAccessor.GetCertificate(rec.Serial, rec.AKI)
+	if err != nil {
+		return fmt.Errorf("ocsp/refresher: failed to load certificate: %w", err)
+	}
+	if len(crs) == 0 {
+		return fmt.Errorf("ocsp/refresher: no certificate on record for serial %s", rec.Serial)
+	}
+
+	p, _ := pem.Decode([]byte(crs[0].PEM))
+	if p == nil {
+		return fmt.Errorf("ocsp/refresher: certificate for serial %s is not valid PEM", rec.Serial)
+	}
+	cert, err := x509.ParseCertificate(p.Bytes)
+	if err != nil {
+		return fmt.Errorf("ocsp/refresher: failed to parse certificate for serial %s: %w", rec.Serial, err)
+	}
+
+	status := "good"
+	var revokedAt time.Time
+	if crs[0].Status == "revoked" {
+		status = "revoked"
+		revokedAt = crs[0].RevokedAt
+	}
+
+	resp, err := r.signer.Sign(ocsp.SignRequest{
+		Certificate: cert,
+		Status:      status,
+		RevokedAt:   revokedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	parsed, err := stdocsp.ParseResponse(resp, nil)
+	if err != nil {
+		return fmt.Errorf("ocsp/refresher: failed to parse freshly signed response for serial %s: %w", rec.Serial, err)
+	}
+
+	return r.dbAccessor.UpsertOCSP(rec.Serial, rec.AKI, string(resp), parsed.NextUpdate)
+}
+
+// jittered returns d scaled by a random factor in
+// [1-jitterFraction, 1+jitterFraction], so that replicas running the
+// same interval don't all scan at once.
+func jittered(d time.Duration) time.Duration {
+	delta := float64(d) * jitterFraction
+	return d + time.Duration(delta*(2*rand.Float64()-1))
+}
@@ -0,0 +1,186 @@
+package refresher
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/cloudflare/cfssl/certdb"
+	"github.com/cloudflare/cfssl/ocsp"
+	stdocsp "golang.org/x/crypto/ocsp"
+)
+
+// fakeAccessor implements the accessor interface this package actually
+// needs, rather than the full certdb.Accessor, and records the arguments
+// UpsertOCSP was called with for assertions.
+type fakeAccessor struct {
+	unexpired []certdb.OCSPRecord
+	certs     map[string][]certdb.CertificateRecord
+
+	upserts []upsertCall
+}
+
+type upsertCall struct {
+	serial, aki, body string
+	expiry            time.Time
+}
+
+func (f *fakeAccessor) GetUnexpiredOCSPs() ([]certdb.OCSPRecord, error) {
+	return f.unexpired, nil
+}
+
+func (f *fakeAccessor) GetCertificate(serial, aki string) ([]certdb.CertificateRecord, error) {
+	return f.certs[serial+aki], nil
+}
+
+func (f *fakeAccessor) UpsertOCSP(serial, aki, body string, expiry time.Time) error {
+	f.upserts = append(f.upserts, upsertCall{serial: serial, aki: aki, body: body, expiry: expiry})
+	return nil
+}
+
+// fakeSigner re-signs with a real issuer key so refreshOne's call to
+// stdocsp.ParseResponse succeeds, the same as a real ocsp.Signer would
+// produce.
+type fakeSigner struct {
+	issuerCert *x509.Certificate
+	issuerKey  *ecdsa.PrivateKey
+}
+
+func (s *fakeSigner) Sign(req ocsp.SignRequest) ([]byte, error) {
+	status := stdocsp.Good
+	if req.Status == "revoked" {
+		status = stdocsp.Revoked
+	}
+	return stdocsp.CreateResponse(s.issuerCert, s.issuerCert, stdocsp.Response{
+		Status:       status,
+		SerialNumber: req.Certificate.SerialNumber,
+		ThisUpdate:   time.Now(),
+		NextUpdate:   time.Now().Add(time.Hour),
+		RevokedAt:    req.RevokedAt,
+	}, s.issuerKey)
+}
+
+// testIssuer builds a self-signed CA certificate/key and a leaf
+// certificate issued by it, standing in for what a real certdb record
+// would contain.
+func testIssuer(t *testing.T) (*x509.Certificate, *ecdsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+
+	issuerKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate issuer key: %v", err)
+	}
+	issuerTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test issuer"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	issuerDER, err := x509.CreateCertificate(rand.Reader, issuerTemplate, issuerTemplate, &issuerKey.PublicKey, issuerKey)
+	if err != nil {
+		t.Fatalf("create issuer cert: %v", err)
+	}
+	issuerCert, err := x509.ParseCertificate(issuerDER)
+	if err != nil {
+		t.Fatalf("parse issuer cert: %v", err)
+	}
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate leaf key: %v", err)
+	}
+	leafTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "leaf.example.test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, issuerCert, &leafKey.PublicKey, issuerKey)
+	if err != nil {
+		t.Fatalf("create leaf cert: %v", err)
+	}
+	leafCert, err := x509.ParseCertificate(leafDER)
+	if err != nil {
+		t.Fatalf("parse leaf cert: %v", err)
+	}
+
+	return issuerCert, issuerKey, leafCert
+}
+
+func TestRefreshOneUpsertsNewExpiry(t *testing.T) {
+	issuerCert, issuerKey, leafCert := testIssuer(t)
+
+	leafPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafCert.Raw}))
+	serial := leafCert.SerialNumber.String()
+	aki := "deadbeef"
+
+	fa := &fakeAccessor{
+		certs: map[string][]certdb.CertificateRecord{
+			serial + aki: {{Serial: serial, AKI: aki, PEM: leafPEM, Status: "good"}},
+		},
+	}
+
+	r := &Refresher{
+		dbAccessor: fa,
+		signer:     &fakeSigner{issuerCert: issuerCert, issuerKey: issuerKey},
+		cfg:        Config{}.withDefaults(),
+	}
+
+	oldExpiry := time.Now().Add(-time.Minute)
+	rec := certdb.OCSPRecord{Serial: serial, AKI: aki, Body: "stale", Expiry: oldExpiry}
+	if err := r.refreshOne(context.Background(), rec); err != nil {
+		t.Fatalf("refreshOne: %v", err)
+	}
+
+	if len(fa.upserts) != 1 {
+		t.Fatalf("got %d UpsertOCSP calls, want 1", len(fa.upserts))
+	}
+	got := fa.upserts[0]
+	if got.serial != serial || got.aki != aki {
+		t.Fatalf("UpsertOCSP called with serial=%q aki=%q, want serial=%q aki=%q", got.serial, got.aki, serial, aki)
+	}
+	if !got.expiry.After(oldExpiry) {
+		t.Fatalf("UpsertOCSP expiry %v did not advance past the stale expiry %v", got.expiry, oldExpiry)
+	}
+}
+
+func TestRunScanOnlyRefreshesDueRecords(t *testing.T) {
+	issuerCert, issuerKey, leafCert := testIssuer(t)
+	leafPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafCert.Raw}))
+	serial := leafCert.SerialNumber.String()
+	aki := "deadbeef"
+
+	fa := &fakeAccessor{
+		certs: map[string][]certdb.CertificateRecord{
+			serial + aki: {{Serial: serial, AKI: aki, PEM: leafPEM, Status: "good"}},
+		},
+		unexpired: []certdb.OCSPRecord{
+			// due: within the refresh window
+			{Serial: serial, AKI: aki, Expiry: time.Now().Add(time.Minute)},
+			// not due: far in the future
+			{Serial: serial, AKI: aki, Expiry: time.Now().Add(48 * time.Hour)},
+		},
+	}
+
+	r := &Refresher{
+		dbAccessor: fa,
+		signer:     &fakeSigner{issuerCert: issuerCert, issuerKey: issuerKey},
+		cfg:        Config{Window: time.Hour}.withDefaults(),
+	}
+
+	r.runScan(context.Background())
+
+	if len(fa.upserts) != 1 {
+		t.Fatalf("got %d UpsertOCSP calls, want 1 (only the record inside the refresh window)", len(fa.upserts))
+	}
+}
@@ -2,26 +2,71 @@ package otel
 
 import (
 	"os"
+	"strconv"
 
 	"github.com/cloudflare/cfssl/log"
 	"github.com/honeycombio/otel-config-go/otelconfig"
 )
 
-// Setup configures the OpenTelemetry.
-func Setup(service string) func() {
+// options holds the configuration used by Setup.
+type options struct {
+	tracesEnabled    bool
+	exporterProtocol otelconfig.ExporterProtocol
+}
+
+// Option is a functional option for configuring Setup.
+type Option func(*options)
+
+// WithTracesEnabled enables or disables trace export.
+func WithTracesEnabled(enabled bool) Option {
+	return func(o *options) {
+		o.tracesEnabled = enabled
+	}
+}
+
+// WithExporterProtocol selects the OTLP wire protocol used to export
+// traces and metrics (HTTP/protobuf or gRPC).
+func WithExporterProtocol(protocol otelconfig.ExporterProtocol) Option {
+	return func(o *options) {
+		o.exporterProtocol = protocol
+	}
+}
+
+// Setup configures OpenTelemetry. Tracing is enabled by default; it, and the
+// exporter protocol, can be overridden either by passing Options or by
+// setting the OTEL_TRACES_ENABLED and OTEL_EXPORTER_OTLP_PROTOCOL
+// environment variables.
+func Setup(service string, opts ...Option) func() {
 	url := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
 	if url == "" {
 		log.Warning("No OTEL_EXPORTER_OTLP_ENDPOINT provided, OpenTelemetry will not be configured")
 		return func() {}
 	}
 
+	o := options{
+		tracesEnabled:    true,
+		exporterProtocol: protocolFromEnv(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if raw := os.Getenv("OTEL_TRACES_ENABLED"); raw != "" {
+		enabled, err := strconv.ParseBool(raw)
+		if err != nil {
+			log.Warningf("invalid OTEL_TRACES_ENABLED value %q, ignoring: %v", raw, err)
+		} else {
+			o.tracesEnabled = enabled
+		}
+	}
+
 	shutdown, err := otelconfig.ConfigureOpenTelemetry(
 		otelconfig.WithServiceName(service),
 		otelconfig.WithExporterEndpoint(url),
-		otelconfig.WithExporterProtocol(otelconfig.ProtocolHTTPProto),
+		otelconfig.WithExporterProtocol(o.exporterProtocol),
 		otelconfig.WithExporterInsecure(true),
 		otelconfig.WithMetricsEnabled(true),
-		otelconfig.WithTracesEnabled(false),
+		otelconfig.WithTracesEnabled(o.tracesEnabled),
 	)
 	if err != nil {
 		log.Warning("Failed to configure OpenTelemetry: %v", err)
@@ -30,3 +75,17 @@ func Setup(service string) func() {
 
 	return shutdown
 }
+
+// protocolFromEnv maps OTEL_EXPORTER_OTLP_PROTOCOL to the otelconfig
+// protocol constant, defaulting to HTTP/protobuf when unset or unrecognized.
+func protocolFromEnv() otelconfig.ExporterProtocol {
+	switch os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL") {
+	case "grpc":
+		return otelconfig.ProtocolGRPC
+	case "http/protobuf", "":
+		return otelconfig.ProtocolHTTPProto
+	default:
+		log.Warningf("unrecognized OTEL_EXPORTER_OTLP_PROTOCOL value, defaulting to http/protobuf")
+		return otelconfig.ProtocolHTTPProto
+	}
+}